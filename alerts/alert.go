@@ -1,11 +1,11 @@
 package alerts
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http/httputil"
-	"strconv"
+	"net/url"
 	"strings"
 
 	splunk "github.com/kuro-jojo/splunk-sdk-go/client"
@@ -15,6 +15,22 @@ import (
 const savedSearchesPath = "services/saved/searches/"
 const triggeredAlertsPath = "services/alerts/fired_alerts/"
 
+// operation names of the requests, given to the middlewares of the client
+const (
+	OperationCreateAlert           = "alerts.create"
+	OperationGetAlert              = "alerts.get"
+	OperationUpdateAlert           = "alerts.update"
+	OperationDeleteAlert           = "alerts.delete"
+	OperationListAlerts            = "alerts.list"
+	OperationEnableAlert           = "alerts.enable"
+	OperationDisableAlert          = "alerts.disable"
+	OperationDispatchAlert         = "alerts.dispatch"
+	OperationAlertHistory          = "alerts.history"
+	OperationGetTriggeredAlerts    = "alerts.fired"
+	OperationGetTriggeredInstances = "alerts.fired_instances"
+	OperationDeleteTriggeredAlert  = "alerts.fired_delete"
+)
+
 type AlertRequest struct {
 	Headers map[string]string
 	Params  AlertParams
@@ -31,12 +47,31 @@ type AlertParams struct {
 	EarliestTime string
 	// latest (exclusive) time bounds for the search
 	LatestTime string
+	// window of a real-time alert such as 5m, the search then runs continuously over the events of the window.
+	// EarliestTime and LatestTime must be empty
+	RealTimeWindow string
+	// type of the alert, AlertTypeCustom if empty
+	AlertType string
+	// comparison of the number of events, hosts or sources with AlertThreshold, required by these types
+	AlertComparator string
+	// integer compared by AlertComparator, a percentage such as 10 or 10% for the perc comparators
+	AlertThreshold string
 	//condition for triggering the alert
-	AlertCondition      string
+	AlertCondition string
+	// severity of the triggered alert, from SeverityDebug to SeverityFatal. Splunk uses SeverityWarn if 0
+	AlertSeverity int
+	// false triggers the actions once for each result instead of once for all of them, splunk uses true if nil
+	AlertDigestMode *bool
+	// false does not record the triggered alerts, which are then missing from the fired alerts. A created alert is tracked if nil
+	AlertTrack *bool
+
 	AlertSuppress       string
 	AlertSuppressPeriod string
-	Actions             string
-	WebhookUrl          string
+	// comma separated names of the actions, see AlertActions to configure them
+	Actions    string
+	WebhookUrl string
+	// actions run when the alert is triggered, with their parameters
+	AlertActions []AlertAction
 }
 
 type splunkAlertEntry struct {
@@ -83,37 +118,32 @@ type Content struct {
 // Creates a new alert from saved search
 func CreateAlert(client *splunk.SplunkClient, spAlert *AlertRequest) error {
 
+	return CreateAlertWithContext(context.Background(), client, spAlert)
+}
+
+// CreateAlertWithContext is like CreateAlert but the post request is bound to ctx
+func CreateAlertWithContext(ctx context.Context, client *splunk.SplunkClient, spAlert *AlertRequest) error {
+
 	// create the endpoint for the request
-	utils.CreateEndpoint(client, savedSearchesPath)
-	spAlert.Params.SearchQuery = utils.ValidateAlertQuery(spAlert.Params.SearchQuery)
-
-	resp, err := PostAlert(client, spAlert)
-
-	var respDump []byte
-	var errDump error
-	if resp != nil {
-		respDump, errDump = httputil.DumpResponse(resp, true)
-		if errDump != nil {
-			fmt.Println(errDump)
-		}
-	}
+	endpoint := utils.CreateEndpoint(client, savedSearchesPath)
+
+	// work on a copy so that the request of the caller is left untouched
+	alert := *spAlert
+	alert.Params.SearchQuery = utils.ValidateAlertQuery(alert.Params.SearchQuery)
+
+	resp, err := PostAlertWithContext(splunk.ContextWithOperation(ctx, OperationCreateAlert), client, endpoint, &alert)
 
 	if err != nil {
-		return fmt.Errorf("alert creation : error while making the post request : %s", err)
+		return fmt.Errorf("alert creation : error while making the post request : %w", err)
 	}
+	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
 	// handle error
-	if !strings.HasPrefix(strconv.Itoa(resp.StatusCode), "2") {
-		status, err := splunk.HandleHttpError(body)
-		switch err {
-		case nil:
-			return fmt.Errorf("alert creation : http error :  %s \nResponse : %v", status, string(respDump))
-		default:
-			return fmt.Errorf("alert creation : http error :  %s \nResponse : %v", resp.Status, string(respDump))
-		}
+	if err := splunk.CheckResponse(resp); err != nil {
+		return fmt.Errorf("alert creation : %w", err)
 	}
 
+	_, err = io.ReadAll(resp.Body)
 	if err != nil {
 		return fmt.Errorf("alert creation : error while getting the body of the post request : %s", err)
 	}
@@ -124,39 +154,31 @@ func CreateAlert(client *splunk.SplunkClient, spAlert *AlertRequest) error {
 // Removes an existing saved search
 func RemoveAlert(client *splunk.SplunkClient, alertName string) error {
 
+	return RemoveAlertWithContext(context.Background(), client, alertName)
+}
+
+// RemoveAlertWithContext is like RemoveAlert but the delete request is bound to ctx
+func RemoveAlertWithContext(ctx context.Context, client *splunk.SplunkClient, alertName string) error {
+
 	// create the endpoint for the request
-	utils.CreateEndpoint(client, savedSearchesPath+alertName)
+	endpoint := utils.CreateEndpoint(client, savedSearchesPath+alertName)
 
 	splunkAlert := AlertRequest{}
 	splunkAlert.Params.Name = alertName
 
-	resp, err := DeleteAlert(client, &splunkAlert)
-
-	var respDump []byte
-	var errDump error
-	if resp != nil {
-		respDump, errDump = httputil.DumpResponse(resp, true)
-		if errDump != nil {
-			fmt.Println(errDump)
-		}
-	}
+	resp, err := DeleteAlertWithContext(splunk.ContextWithOperation(ctx, OperationDeleteAlert), client, endpoint, &splunkAlert)
 
 	if err != nil {
-		return fmt.Errorf("alert Removing : error while making the delete request : %s", err)
+		return fmt.Errorf("alert Removing : error while making the delete request : %w", err)
 	}
+	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
 	// handle error
-	if !strings.HasPrefix(strconv.Itoa(resp.StatusCode), "2") {
-		status, err := splunk.HandleHttpError(body)
-		switch err {
-		case nil:
-			return fmt.Errorf("alert Removing : http error :  %s \nResponse : %v", status, string(respDump))
-		default:
-			return fmt.Errorf("alert Removing : http error :  %s \nResponse : %v", resp.Status, string(respDump))
-		}
+	if err := splunk.CheckResponse(resp); err != nil {
+		return fmt.Errorf("alert Removing : %w", err)
 	}
 
+	_, err = io.ReadAll(resp.Body)
 	if err != nil {
 		return fmt.Errorf("alert Removing : error while getting the body of the delete request : %s", err)
 	}
@@ -167,38 +189,31 @@ func RemoveAlert(client *splunk.SplunkClient, alertName string) error {
 // List saved searches
 func ListAlertsNames(client *splunk.SplunkClient) (splunkAlertList, error) {
 
+	return ListAlertsNamesWithContext(context.Background(), client)
+}
+
+// ListAlertsNamesWithContext is like ListAlertsNames but the get request is bound to ctx
+func ListAlertsNamesWithContext(ctx context.Context, client *splunk.SplunkClient) (splunkAlertList, error) {
+
 	var alertList splunkAlertList
 
 	// create the endpoint for the request
-	utils.CreateEndpoint(client, savedSearchesPath)
+	endpoint := utils.CreateEndpoint(client, savedSearchesPath)
 
-	resp, err := GetAlerts(client)
-
-	var respDump []byte
-	var errDump error
-	if resp != nil {
-		respDump, errDump = httputil.DumpResponse(resp, true)
-		if errDump != nil {
-			fmt.Println(errDump)
-		}
-	}
+	resp, err := GetAlertsWithContext(splunk.ContextWithOperation(ctx, OperationListAlerts), client, endpoint)
 
 	if err != nil {
-		return alertList, fmt.Errorf("alerts' names listing : error while making the get request : %s", err)
+		return alertList, fmt.Errorf("alerts' names listing : error while making the get request : %w", err)
 	}
+	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
 	// handle error
-	if !strings.HasPrefix(strconv.Itoa(resp.StatusCode), "2") {
-		status, err := splunk.HandleHttpError(body)
-		switch err {
-		case nil:
-			return alertList, fmt.Errorf("alerts' names listing : http error :  %s \nResponse : %v", status, string(respDump))
-		default:
-			return alertList, fmt.Errorf("alerts' names listing : http error :  %s \nResponse : %v", resp.Status, string(respDump))
-		}
+	if err := splunk.CheckResponse(resp); err != nil {
+		return alertList, fmt.Errorf("alerts' names listing : %w", err)
 	}
 
+	body, err := io.ReadAll(resp.Body)
+
 	if err != nil {
 		return alertList, fmt.Errorf("alerts' names listing : error while getting the body of the get request : %s", err)
 	}
@@ -213,38 +228,31 @@ func ListAlertsNames(client *splunk.SplunkClient) (splunkAlertList, error) {
 
 func GetTriggeredAlerts(client *splunk.SplunkClient) (TriggeredAlerts, error) {
 
+	return GetTriggeredAlertsWithContext(context.Background(), client)
+}
+
+// GetTriggeredAlertsWithContext is like GetTriggeredAlerts but the get request is bound to ctx
+func GetTriggeredAlertsWithContext(ctx context.Context, client *splunk.SplunkClient) (TriggeredAlerts, error) {
+
 	var triggeredAlerts TriggeredAlerts
 
 	// create the endpoint for the request
-	utils.CreateEndpoint(client, triggeredAlertsPath)
-
-	resp, err := GetAlerts(client)
+	endpoint := utils.CreateEndpoint(client, triggeredAlertsPath)
 
-	var respDump []byte
-	var errDump error
-	if resp != nil {
-		respDump, errDump = httputil.DumpResponse(resp, true)
-		if errDump != nil {
-			fmt.Println(errDump)
-		}
-	}
+	resp, err := GetAlertsWithContext(splunk.ContextWithOperation(ctx, OperationGetTriggeredAlerts), client, endpoint)
 
 	if err != nil {
-		return triggeredAlerts, fmt.Errorf("triggered alerts' names listing : error while making the get request : %s", err)
+		return triggeredAlerts, fmt.Errorf("triggered alerts' names listing : error while making the get request : %w", err)
 	}
+	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
 	// handle error
-	if !strings.HasPrefix(strconv.Itoa(resp.StatusCode), "2") {
-		status, err := splunk.HandleHttpError(body)
-		switch err {
-		case nil:
-			return triggeredAlerts, fmt.Errorf("triggered alerts' names listing : http error :  %s \nResponse : %s", status, string(respDump))
-		default:
-			return triggeredAlerts, fmt.Errorf("triggered alerts' names listing : http error :  %s \nResponse : %s", resp.Status, string(respDump))
-		}
+	if err := splunk.CheckResponse(resp); err != nil {
+		return triggeredAlerts, fmt.Errorf("triggered alerts' names listing : %w", err)
 	}
 
+	body, err := io.ReadAll(resp.Body)
+
 	if err != nil {
 		return triggeredAlerts, fmt.Errorf("triggered alerts' names listing : error while getting the body of the get request : %s", err)
 	}
@@ -259,38 +267,35 @@ func GetTriggeredAlerts(client *splunk.SplunkClient) (TriggeredAlerts, error) {
 
 func GetInstancesOfTriggeredAlert(client *splunk.SplunkClient, link string) (TriggeredInstances, error) {
 
-	var triggeredInstances TriggeredInstances
+	return GetInstancesOfTriggeredAlertWithContext(context.Background(), client, link)
+}
 
-	// create the endpoint for the request
-	utils.CreateEndpoint(client, strings.TrimPrefix(link, "/"))
+// GetInstancesOfTriggeredAlertWithContext is like GetInstancesOfTriggeredAlert but the get request is bound to ctx
+func GetInstancesOfTriggeredAlertWithContext(ctx context.Context, client *splunk.SplunkClient, link string) (TriggeredInstances, error) {
 
-	resp, err := GetAlerts(client)
+	var triggeredInstances TriggeredInstances
 
-	var respDump []byte
-	var errDump error
-	if resp != nil {
-		respDump, errDump = httputil.DumpResponse(resp, true)
-		if errDump != nil {
-			fmt.Println(errDump)
-		}
+	// create the endpoint for the request
+	// the links given by splunk are already escaped
+	if unescapedLink, err := url.PathUnescape(link); err == nil {
+		link = unescapedLink
 	}
+	endpoint := utils.CreateEndpoint(client, strings.TrimPrefix(link, "/"))
+
+	resp, err := GetAlertsWithContext(splunk.ContextWithOperation(ctx, OperationGetTriggeredInstances), client, endpoint)
 
 	if err != nil {
-		return triggeredInstances, fmt.Errorf("triggered instances' names listing : error while making the get request : %s", err)
+		return triggeredInstances, fmt.Errorf("triggered instances' names listing : error while making the get request : %w", err)
 	}
+	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
 	// handle error
-	if !strings.HasPrefix(strconv.Itoa(resp.StatusCode), "2") {
-		status, err := splunk.HandleHttpError(body)
-		switch err {
-		case nil:
-			return triggeredInstances, fmt.Errorf("triggered instances' names listing : http error :  %s \nResponse : %s, LINK : %s", status, string(respDump), client.Endpoint)
-		default:
-			return triggeredInstances, fmt.Errorf("triggered instances' names listing : http error :  %s \nResponse : %s, LINK : %s", status, string(respDump), client.Endpoint)
-		}
+	if err := splunk.CheckResponse(resp); err != nil {
+		return triggeredInstances, fmt.Errorf("triggered instances' names listing : %w", err)
 	}
 
+	body, err := io.ReadAll(resp.Body)
+
 	if err != nil {
 		return triggeredInstances, fmt.Errorf("triggered instances' names listing : error while getting the body of the get request : %w", err)
 	}
@@ -0,0 +1,296 @@
+package alerts
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// names of the actions shipped with splunk
+const (
+	ActionEmail    = "email"
+	ActionWebhook  = "webhook"
+	ActionScript   = "script"
+	ActionLogEvent = "logevent"
+	ActionSlack    = "slack"
+)
+
+// formats of the results included in an email
+const (
+	EmailFormatTable = "table"
+	EmailFormatRaw   = "raw"
+	EmailFormatCSV   = "csv"
+)
+
+var actionNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// AlertAction is an action run when the alert is triggered
+type AlertAction interface {
+	// Name is the name of the action in the actions attribute of the saved search
+	Name() string
+	// Params returns the parameters of the action, without their action.<name>. prefix
+	Params() map[string]string
+	// Validate checks the required parameters of the action
+	Validate() error
+}
+
+// EmailAction sends an email
+type EmailAction struct {
+	To  []string
+	Cc  []string
+	Bcc []string
+	// subject and body of the email, splunk uses its own if empty. Tokens such as $name$ are replaced
+	Subject string
+	Message string
+	// format of the results included in the email : EmailFormatTable (default), EmailFormatRaw or EmailFormatCSV
+	Format string
+	// include the results in the body of the email
+	InlineResults bool
+	// attach the results to the email as a csv file
+	AttachCSV bool
+}
+
+func (a EmailAction) Name() string { return ActionEmail }
+
+func (a EmailAction) Params() map[string]string {
+
+	params := map[string]string{"to": strings.Join(a.To, ",")}
+	if len(a.Cc) > 0 {
+		params["cc"] = strings.Join(a.Cc, ",")
+	}
+	if len(a.Bcc) > 0 {
+		params["bcc"] = strings.Join(a.Bcc, ",")
+	}
+	if a.Subject != "" {
+		params["subject"] = a.Subject
+	}
+	if a.Message != "" {
+		params["message.alert"] = a.Message
+	}
+	if a.Format != "" {
+		params["format"] = a.Format
+	}
+	if a.InlineResults {
+		params["inline"] = "1"
+		params["sendresults"] = "1"
+	}
+	if a.AttachCSV {
+		params["sendcsv"] = "1"
+		params["sendresults"] = "1"
+	}
+	return params
+}
+
+func (a EmailAction) Validate() error {
+
+	if len(a.To) == 0 {
+		return fmt.Errorf("email action : at least one recipient is required")
+	}
+	for _, recipient := range append(append(append([]string{}, a.To...), a.Cc...), a.Bcc...) {
+		if !strings.Contains(recipient, "@") {
+			return fmt.Errorf("email action : invalid recipient %q", recipient)
+		}
+	}
+	switch a.Format {
+	case "", EmailFormatTable, EmailFormatRaw, EmailFormatCSV:
+	default:
+		return fmt.Errorf("email action : unsupported format %q", a.Format)
+	}
+	return nil
+}
+
+// WebhookAction posts the result of the alert to an url
+type WebhookAction struct {
+	URL string
+}
+
+func (a WebhookAction) Name() string { return ActionWebhook }
+
+func (a WebhookAction) Params() map[string]string {
+
+	return map[string]string{"param.url": a.URL}
+}
+
+func (a WebhookAction) Validate() error {
+
+	return validateURL("webhook action", a.URL)
+}
+
+// ScriptAction runs a script of the bin/scripts directory of splunk
+type ScriptAction struct {
+	Filename string
+}
+
+func (a ScriptAction) Name() string { return ActionScript }
+
+func (a ScriptAction) Params() map[string]string {
+
+	return map[string]string{"filename": a.Filename}
+}
+
+func (a ScriptAction) Validate() error {
+
+	if a.Filename == "" {
+		return fmt.Errorf("script action : the filename is required")
+	}
+	if strings.ContainsAny(a.Filename, `/\`) {
+		return fmt.Errorf("script action : %q must be a file name, not a path", a.Filename)
+	}
+	return nil
+}
+
+// LogEventAction writes an event to an index of splunk
+type LogEventAction struct {
+	// the event, tokens such as $result.host$ are replaced
+	Event      string
+	Index      string
+	Sourcetype string
+	Source     string
+	Host       string
+}
+
+func (a LogEventAction) Name() string { return ActionLogEvent }
+
+func (a LogEventAction) Params() map[string]string {
+
+	params := map[string]string{"param.event": a.Event}
+	if a.Index != "" {
+		params["param.index"] = a.Index
+	}
+	if a.Sourcetype != "" {
+		params["param.sourcetype"] = a.Sourcetype
+	}
+	if a.Source != "" {
+		params["param.source"] = a.Source
+	}
+	if a.Host != "" {
+		params["param.host"] = a.Host
+	}
+	return params
+}
+
+func (a LogEventAction) Validate() error {
+
+	if a.Event == "" {
+		return fmt.Errorf("logevent action : the event is required")
+	}
+	return nil
+}
+
+// SlackAction posts a message to slack, the Slack Notification Alert app must be installed on splunk
+type SlackAction struct {
+	Message string
+	// channel or user receiving the message, the default of the app if empty
+	Channel string
+	// webhook used instead of the one configured in the app
+	WebhookURL string
+}
+
+func (a SlackAction) Name() string { return ActionSlack }
+
+func (a SlackAction) Params() map[string]string {
+
+	params := map[string]string{"param.message": a.Message}
+	if a.Channel != "" {
+		params["param.channel"] = a.Channel
+	}
+	if a.WebhookURL != "" {
+		params["param.webhook_url_override"] = a.WebhookURL
+	}
+	return params
+}
+
+func (a SlackAction) Validate() error {
+
+	if a.Message == "" {
+		return fmt.Errorf("slack action : the message is required")
+	}
+	if a.WebhookURL != "" {
+		return validateURL("slack action", a.WebhookURL)
+	}
+	return nil
+}
+
+// CustomAction is any other action, such as the ones added by splunk apps.
+// The keys of the parameters are given without the action.<name>. prefix, for example param.priority
+type CustomAction struct {
+	ActionName string
+	Parameters map[string]string
+}
+
+func (a CustomAction) Name() string { return a.ActionName }
+
+func (a CustomAction) Params() map[string]string {
+
+	params := make(map[string]string, len(a.Parameters))
+	for key, value := range a.Parameters {
+		params[key] = value
+	}
+	return params
+}
+
+func (a CustomAction) Validate() error {
+
+	if !actionNamePattern.MatchString(a.ActionName) {
+		return fmt.Errorf("custom action : invalid name %q", a.ActionName)
+	}
+	for key := range a.Parameters {
+		if key == "" || strings.HasPrefix(key, ".") || strings.HasSuffix(key, ".") {
+			return fmt.Errorf("custom action %s : invalid parameter %q", a.ActionName, key)
+		}
+	}
+	return nil
+}
+
+func validateURL(action string, rawURL string) error {
+
+	if rawURL == "" {
+		return fmt.Errorf("%s : the url is required", action)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s : invalid url %q", action, rawURL)
+	}
+	return nil
+}
+
+// actionParams returns the actions attribute and the parameters of the actions, the names of actions being merged with the legacy ones
+func actionParams(legacyActions string, actions []AlertAction) (url.Values, error) {
+
+	params := url.Values{}
+	var names []string
+	seen := map[string]bool{}
+	addName := func(name string) {
+		if name = strings.TrimSpace(name); name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if legacyActions != "" {
+		for _, name := range strings.Split(legacyActions, ",") {
+			addName(name)
+		}
+	}
+
+	for _, action := range actions {
+		if action == nil {
+			return nil, fmt.Errorf("the alert action must not be nil")
+		}
+		if err := action.Validate(); err != nil {
+			return nil, err
+		}
+		if !actionNamePattern.MatchString(action.Name()) {
+			return nil, fmt.Errorf("invalid action name %q", action.Name())
+		}
+		addName(action.Name())
+		for key, value := range action.Params() {
+			params.Set("action."+action.Name()+"."+key, value)
+		}
+	}
+
+	if len(names) > 0 {
+		params.Set("actions", strings.Join(names, ","))
+	}
+	return params, nil
+}
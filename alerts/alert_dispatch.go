@@ -0,0 +1,152 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	splunk "github.com/kuro-jojo/splunk-sdk-go/client"
+	utils "github.com/kuro-jojo/splunk-sdk-go/pkg/utils"
+)
+
+// DispatchOptions changes a single run of a saved search, its configuration being left untouched
+type DispatchOptions struct {
+	// time bounds of the search, the ones of the saved search if empty
+	EarliestTime string
+	LatestTime   string
+	// time the search is run as, such as -1d@d, to replay a past schedule
+	Now string
+	// other dispatch.* attributes, given without their dispatch. prefix, for example ttl or max_count
+	Dispatch map[string]string
+	// values of the $name$ tokens of the search, given as args.name
+	Args map[string]string
+	// run the actions of the alert if its condition is met
+	TriggerActions bool
+	// run the search even if a previous run is still going on
+	Force bool
+}
+
+// values returns the parameters of the dispatch request
+func (opts *DispatchOptions) values() url.Values {
+
+	params := url.Values{}
+	if opts == nil {
+		return params
+	}
+
+	for key, value := range opts.Dispatch {
+		params.Set("dispatch."+key, value)
+	}
+	for key, value := range opts.Args {
+		params.Set("args."+key, value)
+	}
+	if opts.EarliestTime != "" {
+		params.Set("dispatch.earliest_time", opts.EarliestTime)
+	}
+	if opts.LatestTime != "" {
+		params.Set("dispatch.latest_time", opts.LatestTime)
+	}
+	if opts.Now != "" {
+		params.Set("dispatch.now", opts.Now)
+	}
+	if opts.TriggerActions {
+		params.Set("trigger_actions", "1")
+	}
+	if opts.Force {
+		params.Set("force_dispatch", "1")
+	}
+	return params
+}
+
+// DispatchAlert runs the saved search now and returns the SID of its job, whose results are read with the jobs package.
+// The job runs asynchronously, see splunk.AcquireSearchSlot to hold a search slot until it is done
+func DispatchAlert(ctx context.Context, client *splunk.SplunkClient, alertName string, opts *DispatchOptions) (string, error) {
+
+	// after a network error or a timeout the search may be running already, only a refusal of splunk is retried
+	ctx = splunk.ContextWithRetryOnRefusal(splunk.ContextWithOperation(ctx, OperationDispatchAlert))
+
+	body, err := postSavedSearch(ctx, client, savedSearchesPath+alertName+"/dispatch", opts.values())
+	if err != nil {
+		return "", fmt.Errorf("alert %s : dispatch : %w", alertName, err)
+	}
+
+	var job struct {
+		Sid string `json:"sid"`
+	}
+	if err := json.Unmarshal(body, &job); err != nil {
+		return "", fmt.Errorf("alert %s : dispatch : could not map the job to datastructure: %w", alertName, err)
+	}
+	if job.Sid == "" {
+		return "", fmt.Errorf("alert %s : dispatch : no sid found", alertName)
+	}
+	return job.Sid, nil
+}
+
+// AlertRun is a past or running job of a saved search
+type AlertRun struct {
+	Sid string
+	// time the job was created
+	Published string
+
+	EarliestTime string
+	LatestTime   string
+	IsDone       bool
+	IsFailed     bool
+	IsScheduled  bool
+	IsRealTime   bool
+
+	Attributes Attributes
+}
+
+// AlertHistory returns the jobs of the saved search still known by splunk, selected by opts, nil opts returning all of them
+func AlertHistory(ctx context.Context, client *splunk.SplunkClient, alertName string, opts *ListOptions) ([]AlertRun, error) {
+
+	endpoint := utils.CreateEndpoint(client, savedSearchesPath+alertName+"/history")
+
+	resp, err := splunk.MakeHttpRequestWithContext(splunk.ContextWithOperation(ctx, OperationAlertHistory), client, http.MethodGet, endpoint, nil, opts.values())
+	if err != nil {
+		return nil, fmt.Errorf("alert %s : history : error while making the get request : %w", alertName, err)
+	}
+	defer resp.Body.Close()
+
+	// handle error
+	if err := splunk.CheckResponse(resp); err != nil {
+		return nil, fmt.Errorf("alert %s : history : %w", alertName, err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("alert %s : history : error while getting the body of the get request : %w", alertName, err)
+	}
+
+	var entries struct {
+		Entry []struct {
+			Name      string     `json:"name"`
+			Published string     `json:"published"`
+			Content   Attributes `json:"content"`
+		} `json:"entry"`
+	}
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, fmt.Errorf("could not map history of alert to datastructure: %w", err)
+	}
+
+	runs := make([]AlertRun, 0, len(entries.Entry))
+	for _, entry := range entries.Entry {
+		content := entry.Content
+		runs = append(runs, AlertRun{
+			Sid:          entry.Name,
+			Published:    entry.Published,
+			EarliestTime: content["earliest_time"],
+			LatestTime:   content["latest_time"],
+			IsDone:       content.Bool("isDone"),
+			IsFailed:     content.Bool("isFailed"),
+			IsScheduled:  content.Bool("isScheduled"),
+			IsRealTime:   content.Bool("isRealTimeSearch"),
+			Attributes:   content,
+		})
+	}
+	return runs, nil
+}
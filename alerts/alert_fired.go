@@ -0,0 +1,218 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	splunk "github.com/kuro-jojo/splunk-sdk-go/client"
+	"github.com/kuro-jojo/splunk-sdk-go/jobs"
+	utils "github.com/kuro-jojo/splunk-sdk-go/pkg/utils"
+)
+
+// number of instances fetched by each request of ListTriggeredAlerts when it is asked for a given count
+const triggeredAlertsBatch = 100
+
+// TriggeredAlert is an instance of a fired alert, kept by splunk until it expires or is deleted
+type TriggeredAlert struct {
+	// name of the instance, given to DeleteTriggeredAlert
+	Name            string
+	SavedSearchName string
+	// SID of the job which triggered the alert, its results are read with the jobs package
+	Sid       string
+	Severity  int
+	AlertType string
+	// true if the actions ran once for all the results, false if they ran once for each result
+	DigestMode  bool
+	TriggerTime time.Time
+	// time the instance expires, as rendered by splunk
+	ExpirationTime string
+	// number of alerts fired by the job
+	TriggeredAlerts int
+	// number of results of the job, only read with TriggeredAlertOptions.ReadResultCount. -1 if the job no longer exists or is unknown
+	ResultCount int64
+	// comma separated names of the actions run
+	Actions string
+
+	Attributes Attributes
+}
+
+// TriggeredAlertOptions selects the instances of the fired alerts
+type TriggeredAlertOptions struct {
+	// name of the saved search whose instances are listed, all of them if empty
+	SavedSearchName string
+	// lowest severity of the instances, all of them if 0
+	MinSeverity int
+	// time range of the triggering of the instances, unbounded if zero
+	Since time.Time
+	Until time.Time
+	// read the result count of the job of each instance, which takes a request per instance
+	ReadResultCount bool
+	// Count is the number of matching instances to return, all of them if 0. Offset is the NextOffset of the previous page.
+	// Search, SortKey and SortDir apply to the instances on splunk
+	ListOptions
+}
+
+// TriggeredAlertPage is a page of the instances of the fired alerts
+type TriggeredAlertPage struct {
+	// instances matching the filters, Count of them unless the page is the last one
+	Alerts []TriggeredAlert
+	// offset of the next page, 0 if this page is the last one
+	NextOffset int
+}
+
+// ListTriggeredAlerts returns a page of the instances of the fired alerts selected by opts, nil opts returning all of them.
+// The severity and time filters are applied by the SDK, which fetches the instances until it has found Count of them
+func ListTriggeredAlerts(ctx context.Context, client *splunk.SplunkClient, opts *TriggeredAlertOptions) (*TriggeredAlertPage, error) {
+
+	if opts == nil {
+		opts = &TriggeredAlertOptions{}
+	}
+	if opts.MinSeverity < 0 || opts.MinSeverity > SeverityFatal {
+		return nil, fmt.Errorf("triggered alerts listing : the severity must be between %d and %d, got %d", SeverityDebug, SeverityFatal, opts.MinSeverity)
+	}
+
+	// the instances of every saved search are listed with the wildcard
+	savedSearchName := opts.SavedSearchName
+	if savedSearchName == "" {
+		savedSearchName = splunk.NamespaceWildcard
+	}
+	endpoint := utils.CreateEndpoint(client, triggeredAlertsPath+savedSearchName)
+
+	batch := opts.ListOptions
+	if batch.Count > 0 && batch.Count < triggeredAlertsBatch {
+		batch.Count = triggeredAlertsBatch
+	}
+
+	page := &TriggeredAlertPage{Alerts: []TriggeredAlert{}}
+	for {
+		entries, total, err := getTriggeredAlerts(ctx, client, endpoint, batch.values())
+		if err != nil {
+			return nil, fmt.Errorf("triggered alerts listing : %w", err)
+		}
+
+		for i, entry := range entries {
+			alert := newTriggeredAlert(entry.Name, entry.Content)
+			if alert.Severity < opts.MinSeverity ||
+				(!opts.Since.IsZero() && alert.TriggerTime.Before(opts.Since)) ||
+				(!opts.Until.IsZero() && !alert.TriggerTime.Before(opts.Until)) {
+				continue
+			}
+			if opts.ReadResultCount {
+				if alert.ResultCount, err = jobResultCount(ctx, client, alert.Sid); err != nil {
+					return nil, fmt.Errorf("triggered alerts listing : instance %s : %w", alert.Name, err)
+				}
+			}
+			page.Alerts = append(page.Alerts, alert)
+
+			if opts.Count > 0 && len(page.Alerts) == opts.Count {
+				if next := batch.Offset + i + 1; next < total {
+					page.NextOffset = next
+				}
+				return page, nil
+			}
+		}
+
+		batch.Offset += len(entries)
+		if batch.Count == 0 || len(entries) == 0 || batch.Offset >= total {
+			return page, nil
+		}
+	}
+}
+
+// triggeredAlertEntry is an instance of a fired alert returned by splunk
+type triggeredAlertEntry struct {
+	Name    string     `json:"name"`
+	Content Attributes `json:"content"`
+}
+
+// getTriggeredAlerts makes a get request to a fired_alerts endpoint and returns its entries and the number of entries on splunk
+func getTriggeredAlerts(ctx context.Context, client *splunk.SplunkClient, endpoint string, params url.Values) ([]triggeredAlertEntry, int, error) {
+
+	resp, err := splunk.MakeHttpRequestWithContext(splunk.ContextWithOperation(ctx, OperationGetTriggeredInstances), client, http.MethodGet, endpoint, nil, params)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error while making the get request : %w", err)
+	}
+	defer resp.Body.Close()
+
+	// handle error
+	if err := splunk.CheckResponse(resp); err != nil {
+		return nil, 0, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error while getting the body of the get request : %w", err)
+	}
+
+	var entries struct {
+		Paging struct {
+			Total int `json:"total"`
+		} `json:"paging"`
+		Entry []triggeredAlertEntry `json:"entry"`
+	}
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, 0, fmt.Errorf("could not map list of triggered alerts to datastructure: %w", err)
+	}
+	return entries.Entry, entries.Paging.Total, nil
+}
+
+// jobResultCount returns the number of results of the job, -1 if it no longer exists or the instance has no job
+func jobResultCount(ctx context.Context, client *splunk.SplunkClient, sid string) (int64, error) {
+
+	if sid == "" {
+		return -1, nil
+	}
+	status, err := jobs.GetJobStatus(ctx, client, sid)
+	if errors.Is(err, splunk.ErrNotFound) {
+		return -1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return status.ResultCount, nil
+}
+
+func newTriggeredAlert(name string, content Attributes) TriggeredAlert {
+
+	alert := TriggeredAlert{
+		Name:            name,
+		SavedSearchName: content["savedsearch_name"],
+		Sid:             content["sid"],
+		Severity:        content.Int("severity"),
+		AlertType:       content["alert_type"],
+		DigestMode:      content.Bool("digest_mode"),
+		ExpirationTime:  content["expiration_time_rendered"],
+		TriggeredAlerts: content.Int("triggered_alerts"),
+		Actions:         content["actions"],
+		Attributes:      content,
+	}
+	if seconds, err := strconv.ParseFloat(content["trigger_time"], 64); err == nil {
+		alert.TriggerTime = time.Unix(0, int64(seconds*float64(time.Second)))
+	}
+	return alert
+}
+
+// DeleteTriggeredAlert deletes an instance of a fired alert, which acknowledges it
+func DeleteTriggeredAlert(ctx context.Context, client *splunk.SplunkClient, name string) error {
+
+	endpoint := utils.CreateEndpoint(client, triggeredAlertsPath+name)
+
+	resp, err := DeleteAlertWithContext(splunk.ContextWithOperation(ctx, OperationDeleteTriggeredAlert), client, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("triggered alert %s : error while making the delete request : %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	// handle error
+	if err := splunk.CheckResponse(resp); err != nil {
+		return fmt.Errorf("triggered alert %s : %w", name, err)
+	}
+	return nil
+}
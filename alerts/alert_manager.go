@@ -1,86 +1,124 @@
 package alerts
 
 import (
+	"context"
 	"net/http"
 	"net/url"
 
 	splunk "github.com/kuro-jojo/splunk-sdk-go/client"
 )
 
-func PostAlert(client *splunk.SplunkClient, spAlert *AlertRequest) (*http.Response, error) {
+func PostAlert(client *splunk.SplunkClient, endpoint string, spAlert *AlertRequest) (*http.Response, error) {
 
-	return HttpAlertRequest(client, http.MethodPost, spAlert)
+	return PostAlertWithContext(context.Background(), client, endpoint, spAlert)
 }
 
-func GetAlerts(client *splunk.SplunkClient) (*http.Response, error) {
+func PostAlertWithContext(ctx context.Context, client *splunk.SplunkClient, endpoint string, spAlert *AlertRequest) (*http.Response, error) {
 
-	return HttpAlertRequest(client, http.MethodGet, nil)
+	return HttpAlertRequestWithContext(ctx, client, http.MethodPost, endpoint, spAlert)
 }
 
-func DeleteAlert(client *splunk.SplunkClient, spAlert *AlertRequest) (*http.Response, error) {
+func GetAlerts(client *splunk.SplunkClient, endpoint string) (*http.Response, error) {
 
-	return HttpAlertRequest(client, "DELETE", spAlert)
+	return GetAlertsWithContext(context.Background(), client, endpoint)
 }
 
-func HttpAlertRequest(client *splunk.SplunkClient, method string, spAlert *AlertRequest) (*http.Response, error) {
+func GetAlertsWithContext(ctx context.Context, client *splunk.SplunkClient, endpoint string) (*http.Response, error) {
+
+	return HttpAlertRequestWithContext(ctx, client, http.MethodGet, endpoint, nil)
+}
+
+func DeleteAlert(client *splunk.SplunkClient, endpoint string, spAlert *AlertRequest) (*http.Response, error) {
+
+	return DeleteAlertWithContext(context.Background(), client, endpoint, spAlert)
+}
+
+func DeleteAlertWithContext(ctx context.Context, client *splunk.SplunkClient, endpoint string, spAlert *AlertRequest) (*http.Response, error) {
+
+	return HttpAlertRequestWithContext(ctx, client, http.MethodDelete, endpoint, spAlert)
+}
+
+func HttpAlertRequest(client *splunk.SplunkClient, method string, endpoint string, spAlert *AlertRequest) (*http.Response, error) {
+
+	return HttpAlertRequestWithContext(context.Background(), client, method, endpoint, spAlert)
+}
+
+// HttpAlertRequestWithContext is like HttpAlertRequest but the request is bound to ctx
+func HttpAlertRequestWithContext(ctx context.Context, client *splunk.SplunkClient, method string, endpoint string, spAlert *AlertRequest) (*http.Response, error) {
 
 	if spAlert == nil {
 		spAlert = &AlertRequest{}
 	}
 
-	spAlert.Params.OutputMode = "json"
-
-	// parameters of the request
+	// parameters of the request : the request itself is not modified since it may be shared
 	params := url.Values{}
-	params.Add("output_mode", spAlert.Params.OutputMode)
-
 	if method == http.MethodPost {
-
-		if spAlert.Params.Name != "" {
-			params.Add("name", spAlert.Params.Name)
-		}
-		if spAlert.Params.Actions != "" {
-			params.Add("actions", spAlert.Params.Actions)
-		}
-		if spAlert.Params.WebhookUrl != "" {
-			params.Add("action.webhook.param.url", spAlert.Params.WebhookUrl)
-		}
-		if spAlert.Params.SearchQuery != "" {
-			params.Add("search", spAlert.Params.SearchQuery)
-		}
-		if spAlert.Params.CronSchedule != "" {
-			params.Add("cron_schedule", spAlert.Params.CronSchedule)
-		}
-		if spAlert.Params.AlertCondition != "" {
-			params.Add("alert_condition", spAlert.Params.AlertCondition)
-		}
-		if spAlert.Params.AlertSuppress != "" {
-			params.Add("alert.suppress", spAlert.Params.AlertSuppress)
-		}
-		if spAlert.Params.AlertSuppressPeriod != "" {
-			params.Add("alert.suppress.period", spAlert.Params.AlertSuppressPeriod)
+		var err error
+		if params, err = alertParams(spAlert, true); err != nil {
+			return nil, err
 		}
+	}
+	params.Add("output_mode", "json")
 
-		params.Add("is_scheduled", "1")
+	headers := spAlert.Headers
+	if headers == nil {
+		headers = map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
+	}
+	return splunk.MakeHttpRequestWithContext(ctx, client, method, endpoint, headers, params)
+}
 
-		if spAlert.Params.EarliestTime != "" {
-			params.Add("dispatch.earliest_time", spAlert.Params.EarliestTime)
-		}
-		if spAlert.Params.LatestTime != "" {
-			params.Add("dispatch.latest_time", spAlert.Params.LatestTime)
+// alertParams returns the attributes of the saved search set in the request, with the defaults of the creation if create is true.
+// The request is validated on creation only, an update being validated with the attributes of the saved search
+func alertParams(spAlert *AlertRequest, create bool) (url.Values, error) {
+
+	if create {
+		if err := validateAlertType(&spAlert.Params); err != nil {
+			return nil, err
 		}
+	}
+	params, err := actionParams(spAlert.Params.Actions, spAlert.Params.AlertActions)
+	if err != nil {
+		return nil, err
+	}
 
-		params.Add("alert_type", "custom")
+	if spAlert.Params.Name != "" {
+		params.Add("name", spAlert.Params.Name)
+	}
+	// a webhook action of AlertActions takes precedence
+	if spAlert.Params.WebhookUrl != "" && !params.Has("action.webhook.param.url") {
+		params.Add("action.webhook.param.url", spAlert.Params.WebhookUrl)
+	}
+	if spAlert.Params.SearchQuery != "" {
+		params.Add("search", spAlert.Params.SearchQuery)
+	}
+	if spAlert.Params.CronSchedule != "" {
+		params.Add("cron_schedule", spAlert.Params.CronSchedule)
+	}
+	if spAlert.Params.AlertSuppress != "" {
+		params.Add("alert.suppress", spAlert.Params.AlertSuppress)
+	}
+	if spAlert.Params.AlertSuppressPeriod != "" {
+		params.Add("alert.suppress.period", spAlert.Params.AlertSuppressPeriod)
+	}
 
-		if spAlert.Params.Description != "" {
-			params.Add("description", spAlert.Params.Description)
-		}
+	if create {
+		params.Add("is_scheduled", "1")
+	}
 
-		params.Add("alert.track", "1")
+	if spAlert.Params.EarliestTime != "" {
+		params.Add("dispatch.earliest_time", spAlert.Params.EarliestTime)
+	}
+	if spAlert.Params.LatestTime != "" {
+		params.Add("dispatch.latest_time", spAlert.Params.LatestTime)
+	}
 
+	for name, values := range alertTypeParams(&spAlert.Params, create) {
+		params[name] = values
 	}
-	if spAlert.Headers == nil {
-		spAlert.Headers = map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
+
+	if spAlert.Params.Description != "" {
+		params.Add("description", spAlert.Params.Description)
 	}
-	return splunk.MakeHttpRequest(client, method, spAlert.Headers, params)
+
+	return params, nil
 }
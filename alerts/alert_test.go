@@ -0,0 +1,612 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	splunk "github.com/kuro-jojo/splunk-sdk-go/client"
+	splunkTest "github.com/kuro-jojo/splunk-sdk-go/pkg/utils"
+)
+
+const savedSearchResponse = `{
+	"entry": [{
+		"name": "disk full",
+		"acl": {"owner": "admin", "app": "search", "sharing": "app"},
+		"content": {
+			"search": "index=os disk_usage>90",
+			"description": "the disk is almost full",
+			"cron_schedule": "*/5 * * * *",
+			"is_scheduled": true,
+			"disabled": false,
+			"alert_type": "number of events",
+			"alert_comparator": "greater than",
+			"alert_threshold": "0",
+			"alert.severity": 4,
+			"alert.digest_mode": "1",
+			"alert.track": false,
+			"actions": "email,webhook",
+			"action.email.to": "oncall@example.com",
+			"dispatch.earliest_time": "-5m",
+			"next_scheduled_time": null
+		}
+	}]
+}`
+
+// newTestServer starts a splunk mock recording the requests it receives
+func newTestServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) (*splunk.SplunkClient, func() []*http.Request) {
+
+	var mu sync.Mutex
+	var requests []*http.Request
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		mu.Lock()
+		requests = append(requests, r)
+		mu.Unlock()
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	client := splunk.NewClientAuthenticatedByToken(
+		&http.Client{
+			Timeout: time.Duration(60) * time.Second,
+		},
+		splunkTest.GetTestHostname(server),
+		splunkTest.GetTestPort(server),
+		splunkTest.GetTestToken(),
+		true,
+	)
+
+	return client, func() []*http.Request {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]*http.Request(nil), requests...)
+	}
+}
+
+func TestGetAlert(t *testing.T) {
+
+	client, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/saved/searches/disk full" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"messages":[{"type":"ERROR","text":"Could not find object id=unknown"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(savedSearchResponse))
+	})
+
+	alert, err := GetAlert(context.Background(), client, "disk full")
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+
+	if alert.Name != "disk full" || alert.ACL.App != "search" || !alert.IsScheduled || alert.Disabled || alert.AlertSeverity != 4 ||
+		!alert.AlertDigestMode || alert.AlertTrack || alert.AlertType != "number of events" || alert.Actions != "email,webhook" {
+		t.Fatalf("Expected the typed configuration of the alert but got %+v.", alert)
+	}
+	if alert.Attributes["action.email.to"] != "oncall@example.com" || alert.Attributes["alert.severity"] != "4" {
+		t.Fatalf("Expected every attribute of the alert but got %v.", alert.Attributes)
+	}
+	if _, ok := alert.Attributes["next_scheduled_time"]; ok {
+		t.Fatalf("Expected the null attributes to be skipped.")
+	}
+
+	if _, err := GetAlert(context.Background(), client, "unknown"); !errors.Is(err, splunk.ErrNotFound) {
+		t.Fatalf("Expected %v but got %v.", splunk.ErrNotFound, err)
+	}
+}
+
+func TestUpdateAlert(t *testing.T) {
+
+	client, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(savedSearchResponse))
+	})
+
+	spAlert := AlertRequest{
+		Params: AlertParams{
+			Name:         "ignored",
+			SearchQuery:  "search index=os disk_usage>90",
+			CronSchedule: "*/5 * * * *",
+			Description:  "the disk is full",
+			EarliestTime: "-10m",
+		},
+	}
+	if err := UpdateAlert(context.Background(), client, "disk full", &spAlert); err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+
+	// only the changed attributes are posted
+	received := requests()
+	if len(received) != 2 || received[1].Method != http.MethodPost || received[1].URL.Path != "/services/saved/searches/disk full" {
+		t.Fatalf("Expected a get and a post request but got %v.", received)
+	}
+	expected := url.Values{
+		"description":            {"the disk is full"},
+		"dispatch.earliest_time": {"-10m"},
+		"output_mode":            {"json"},
+	}
+	if form := received[1].PostForm; form.Encode() != expected.Encode() {
+		t.Fatalf("Expected %v but got %v.", expected, form)
+	}
+	if spAlert.Params.Name != "ignored" || spAlert.Params.SearchQuery != "search index=os disk_usage>90" {
+		t.Fatalf("Expected the request of the caller to be untouched but got %+v.", spAlert.Params)
+	}
+
+	// nothing to change
+	spAlert.Params.Description, spAlert.Params.EarliestTime = "the disk is almost full", "-5m"
+	if err := UpdateAlert(context.Background(), client, "disk full", &spAlert); err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	if received := requests(); len(received) != 3 {
+		t.Fatalf("Expected no post request but got %d requests.", len(received))
+	}
+}
+
+func TestListAlerts(t *testing.T) {
+
+	client, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(savedSearchResponse))
+	})
+
+	alerts, err := ListAlerts(context.Background(), client, &ListOptions{Search: "name=disk*", Count: 10, Offset: 20, SortKey: "name", SortDir: "desc"})
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	if len(alerts) != 1 || alerts[0].Name != "disk full" {
+		t.Fatalf("Expected 1 alert but got %+v.", alerts)
+	}
+
+	query := requests()[0].URL.Query()
+	for param, expected := range map[string]string{"search": "name=disk*", "count": "10", "offset": "20", "sort_key": "name", "sort_dir": "desc", "output_mode": "json"} {
+		if value := query.Get(param); value != expected {
+			t.Errorf("Expected %s=%s but got %s.", param, expected, value)
+		}
+	}
+
+	if _, err := ListAlerts(context.Background(), client, nil); err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	if count := requests()[1].URL.Query().Get("count"); count != "0" {
+		t.Fatalf("Expected all the alerts but got count=%s.", count)
+	}
+}
+
+func TestAlertActions(t *testing.T) {
+
+	client, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{}`))
+	})
+
+	spAlert := AlertRequest{
+		Params: AlertParams{
+			Name:         "disk full",
+			SearchQuery:  "index=os disk_usage>90",
+			CronSchedule: "*/5 * * * *",
+			Actions:      "email",
+			AlertActions: []AlertAction{
+				EmailAction{To: []string{"oncall@example.com", "ops@example.com"}, Subject: "$name$ triggered", Format: EmailFormatCSV, InlineResults: true},
+				WebhookAction{URL: "https://hooks.example.com/splunk"},
+				LogEventAction{Event: "disk full on $result.host$", Index: "alerts"},
+				ScriptAction{Filename: "page.sh"},
+				SlackAction{Message: "disk full", Channel: "#ops"},
+				CustomAction{ActionName: "pagerduty", Parameters: map[string]string{"param.integration_key": "abc"}},
+			},
+		},
+	}
+	if err := CreateAlert(client, &spAlert); err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+
+	form := requests()[0].PostForm
+	for param, expected := range map[string]string{
+		"actions":                                "email,webhook,logevent,script,slack,pagerduty",
+		"action.email.to":                        "oncall@example.com,ops@example.com",
+		"action.email.subject":                   "$name$ triggered",
+		"action.email.format":                    "csv",
+		"action.email.inline":                    "1",
+		"action.email.sendresults":               "1",
+		"action.webhook.param.url":               "https://hooks.example.com/splunk",
+		"action.logevent.param.event":            "disk full on $result.host$",
+		"action.logevent.param.index":            "alerts",
+		"action.script.filename":                 "page.sh",
+		"action.slack.param.message":             "disk full",
+		"action.slack.param.channel":             "#ops",
+		"action.pagerduty.param.integration_key": "abc",
+	} {
+		if values := form[param]; len(values) != 1 || values[0] != expected {
+			t.Errorf("Expected %s=%s but got %v.", param, expected, values)
+		}
+	}
+
+	// the required fields are checked before any request
+	for _, action := range []AlertAction{
+		EmailAction{},
+		EmailAction{To: []string{"oncall"}},
+		EmailAction{To: []string{"oncall@example.com"}, Format: "pdf"},
+		WebhookAction{URL: "hooks.example.com"},
+		ScriptAction{Filename: "../page.sh"},
+		LogEventAction{Index: "alerts"},
+		SlackAction{Channel: "#ops"},
+		CustomAction{ActionName: "page duty"},
+		CustomAction{ActionName: "pagerduty", Parameters: map[string]string{"": "abc"}},
+	} {
+		spAlert.Params.AlertActions = []AlertAction{action}
+		if err := CreateAlert(client, &spAlert); err == nil {
+			t.Errorf("Expected an error for %#v.", action)
+		}
+	}
+	if received := requests(); len(received) != 1 {
+		t.Fatalf("Expected no request for the invalid actions but got %d requests.", len(received))
+	}
+}
+
+func TestAlertTypes(t *testing.T) {
+
+	var perResult atomic.Bool
+	client, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			response := savedSearchResponse
+			if perResult.Load() {
+				response = strings.Replace(response, `"alert.digest_mode": "1"`, `"alert.digest_mode": "0"`, 1)
+			}
+			_, _ = w.Write([]byte(response))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{}`))
+	})
+
+	// the defaults of the creation are kept
+	spAlert := AlertRequest{
+		Params: AlertParams{Name: "errors", SearchQuery: "index=app level=error", CronSchedule: "*/5 * * * *"},
+	}
+	if err := CreateAlert(client, &spAlert); err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	form := requests()[0].PostForm
+	if form.Get("alert_type") != AlertTypeCustom || form.Get("is_scheduled") != "1" || form.Get("alert.track") != "1" {
+		t.Fatalf("Expected a scheduled and tracked custom alert but got %v.", form)
+	}
+
+	spAlert.Params = AlertParams{
+		Name:            "errors",
+		SearchQuery:     "index=app level=error",
+		CronSchedule:    "* * * * *",
+		RealTimeWindow:  "5m",
+		AlertType:       AlertTypeNumberOfEvents,
+		AlertComparator: ComparatorRisesByPerc,
+		AlertThreshold:  "50%",
+		AlertSeverity:   SeveritySevere,
+		AlertDigestMode: Bool(false),
+		AlertTrack:      Bool(false),
+	}
+	if err := CreateAlert(client, &spAlert); err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	form = requests()[1].PostForm
+	for param, expected := range map[string]string{
+		"alert_type":             "number of events",
+		"alert_comparator":       "rises by perc",
+		"alert_threshold":        "50",
+		"alert.severity":         "5",
+		"alert.digest_mode":      "0",
+		"alert.track":            "0",
+		"dispatch.earliest_time": "rt-5m",
+		"dispatch.latest_time":   "rt",
+	} {
+		if values := form[param]; len(values) != 1 || values[0] != expected {
+			t.Errorf("Expected %s=%s but got %v.", param, expected, values)
+		}
+	}
+
+	// the defaults of the creation are not applied to an update
+	if err := UpdateAlert(context.Background(), client, "disk full", &AlertRequest{Params: AlertParams{AlertSeverity: SeverityFatal}}); err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	received := requests()
+	expected := url.Values{"alert.severity": {"6"}, "output_mode": {"json"}}
+	if form := received[len(received)-1].PostForm; form.Encode() != expected.Encode() {
+		t.Fatalf("Expected %v but got %v.", expected, form)
+	}
+
+	// an update reverts an untracked alert triggering once for each result
+	perResult.Store(true)
+	if err := UpdateAlert(context.Background(), client, "disk full", &AlertRequest{Params: AlertParams{AlertDigestMode: Bool(true), AlertTrack: Bool(true)}}); err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	received = requests()
+	expected = url.Values{"alert.digest_mode": {"1"}, "alert.track": {"1"}, "output_mode": {"json"}}
+	if form := received[len(received)-1].PostForm; form.Encode() != expected.Encode() {
+		t.Fatalf("Expected %v but got %v.", expected, form)
+	}
+
+	// an update of the threshold keeps the type and the comparator of the saved search
+	for _, params := range []AlertParams{
+		{AlertThreshold: "10"},
+		{AlertComparator: ComparatorLessThan, AlertThreshold: "10"},
+	} {
+		if err := UpdateAlert(context.Background(), client, "disk full", &AlertRequest{Params: params}); err != nil {
+			t.Fatalf("Got an error for %+v : %s", params, err)
+		}
+		received = requests()
+		if last := received[len(received)-1]; last.Method != http.MethodPost || last.PostForm.Get("alert_threshold") != "10" {
+			t.Fatalf("Expected the threshold to be posted for %+v but got %v.", params, last.PostForm)
+		}
+	}
+
+	// an update is validated with the type of the saved search
+	for _, params := range []AlertParams{
+		{AlertCondition: "search count > 2"},
+		{AlertComparator: "at least"},
+		{AlertThreshold: "2.5"},
+		{AlertType: AlertTypeCustom, AlertThreshold: "10"},
+	} {
+		if err := UpdateAlert(context.Background(), client, "disk full", &AlertRequest{Params: params}); err == nil {
+			t.Errorf("Expected an error for %+v.", params)
+		}
+	}
+	if count := len(requests()); count != len(received)+4 {
+		t.Fatalf("Expected no post request for the invalid updates but got %d more requests.", count-len(received)-4)
+	}
+	received = requests()
+
+	for _, params := range []AlertParams{
+		{AlertType: "sometimes"},
+		{AlertType: "number of results", AlertComparator: ComparatorGreaterThan, AlertThreshold: "10"},
+		{AlertType: AlertTypeNumberOfEvents, AlertThreshold: "10"},
+		{AlertType: AlertTypeNumberOfEvents, AlertComparator: "at least", AlertThreshold: "10"},
+		{AlertType: AlertTypeNumberOfEvents, AlertComparator: ComparatorGreaterThan},
+		{AlertType: AlertTypeNumberOfEvents, AlertComparator: ComparatorGreaterThan, AlertThreshold: "10%"},
+		{AlertType: AlertTypeNumberOfEvents, AlertComparator: ComparatorRisesBy, AlertThreshold: "10%"},
+		{AlertType: AlertTypeNumberOfEvents, AlertComparator: ComparatorGreaterThan, AlertThreshold: "2.5"},
+		{AlertType: AlertTypeNumberOfEvents, AlertComparator: ComparatorDropsByPerc, AlertThreshold: "-10%"},
+		{AlertType: AlertTypeAlways, AlertComparator: ComparatorGreaterThan, AlertThreshold: "10"},
+		{AlertType: AlertTypeNumberOfHosts, AlertComparator: ComparatorLessThan, AlertThreshold: "2", AlertCondition: "search count > 2"},
+		{AlertSeverity: 7},
+		{RealTimeWindow: "5m", EarliestTime: "-5m"},
+	} {
+		params.Name, params.SearchQuery = "errors", "index=app level=error"
+		if err := CreateAlert(client, &AlertRequest{Params: params}); err == nil {
+			t.Errorf("Expected an error for %+v.", params)
+		}
+	}
+	if count := len(requests()); count != len(received) {
+		t.Fatalf("Expected no request for the invalid alerts but got %d more requests.", count-len(received))
+	}
+}
+
+func TestDispatchAlert(t *testing.T) {
+
+	client, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/services/saved/searches/disk full/dispatch":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"sid":"admin__admin__search__RMD5disk_at_1700000000_1"}`))
+		case "/services/saved/searches/disk full/history":
+			_, _ = w.Write([]byte(`{"entry":[{
+				"name": "scheduler__admin__search__RMD5disk_at_1700000000_1",
+				"published": "2023-11-14T22:13:20+00:00",
+				"content": {"earliest_time": "2023-11-14T22:08:20+00:00", "latest_time": "2023-11-14T22:13:20+00:00",
+					"isDone": true, "isFailed": false, "isScheduled": true, "isRealTimeSearch": false, "ttl": 86400}
+			}]}`))
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	})
+	ctx := context.Background()
+
+	if err := DisableAlert(ctx, client, "disk full"); err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	if err := EnableAlert(ctx, client, "disk full"); err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+
+	sid, err := DispatchAlert(ctx, client, "disk full", &DispatchOptions{
+		EarliestTime:   "-1h",
+		Dispatch:       map[string]string{"ttl": "600"},
+		Args:           map[string]string{"host": "web01"},
+		TriggerActions: true,
+	})
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	if sid != "admin__admin__search__RMD5disk_at_1700000000_1" {
+		t.Fatalf("Expected the SID of the job but got %s.", sid)
+	}
+
+	received := requests()
+	for i, path := range []string{"/services/saved/searches/disk full/disable", "/services/saved/searches/disk full/enable", "/services/saved/searches/disk full/dispatch"} {
+		if received[i].Method != http.MethodPost || received[i].URL.Path != path {
+			t.Fatalf("Expected a post request to %s but got %s %s.", path, received[i].Method, received[i].URL.Path)
+		}
+	}
+	expected := url.Values{
+		"dispatch.earliest_time": {"-1h"},
+		"dispatch.ttl":           {"600"},
+		"args.host":              {"web01"},
+		"trigger_actions":        {"1"},
+		"output_mode":            {"json"},
+	}
+	if form := received[2].PostForm; form.Encode() != expected.Encode() {
+		t.Fatalf("Expected %v but got %v.", expected, form)
+	}
+
+	runs, err := AlertHistory(ctx, client, "disk full", &ListOptions{Count: 5})
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	if len(runs) != 1 || runs[0].Sid != "scheduler__admin__search__RMD5disk_at_1700000000_1" || !runs[0].IsDone || !runs[0].IsScheduled ||
+		runs[0].IsRealTime || runs[0].EarliestTime != "2023-11-14T22:08:20+00:00" || runs[0].Attributes.Int("ttl") != 86400 {
+		t.Fatalf("Expected the past run of the alert but got %+v.", runs)
+	}
+	if count := requests()[3].URL.Query().Get("count"); count != "5" {
+		t.Fatalf("Expected count=5 but got %s.", count)
+	}
+
+	if _, err := DispatchAlert(ctx, client, "unknown", nil); err == nil {
+		t.Fatalf("Expected an error for a response without SID.")
+	}
+}
+
+func TestTriggeredAlerts(t *testing.T) {
+
+	// instances of the saved search, splunk returning at most 2 of them by request
+	instances := []string{
+		`{"name": "scheduler__admin__search__RMD5disk_at_1700000000_1", "content": {"savedsearch_name": "disk full", "sid": "scheduler__admin__search__RMD5disk_at_1700000000_1",
+			"severity": 5, "alert_type": "number of events", "digest_mode": true, "trigger_time": 1700000000, "expiration_time_rendered": "2023-11-15 22:13:20 UTC",
+			"triggered_alerts": "1", "actions": "email"}}`,
+		`{"name": "scheduler__admin__search__RMD5disk_at_1700003600_2", "content": {"savedsearch_name": "disk full", "severity": 2, "trigger_time": 1700003600}}`,
+		`{"name": "scheduler__admin__search__RMD5disk_at_1700007200_3", "content": {"savedsearch_name": "disk full", "severity": 1, "trigger_time": 1700007200}}`,
+		`{"name": "scheduler__admin__search__RMD5disk_at_1700010800_4", "content": {"savedsearch_name": "disk full", "sid": "expired", "severity": 4, "trigger_time": 1700010800}}`,
+		`{"name": "scheduler__admin__search__RMD5disk_at_1700090000_5", "content": {"savedsearch_name": "disk full", "severity": 6, "trigger_time": 1700090000}}`,
+	}
+	client, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodDelete:
+			_, _ = w.Write([]byte(`{}`))
+		case r.URL.Path == "/services/search/v2/jobs/scheduler__admin__search__RMD5disk_at_1700000000_1":
+			_, _ = w.Write([]byte(`{"entry":[{"content":{"dispatchState":"DONE","resultCount":12}}]}`))
+		case strings.TrimSuffix(r.URL.Path, "/") == "/services/search/v2/jobs":
+			// the jobs collection, not the job of an instance
+			_, _ = w.Write([]byte(`{"entry":[{"content":{"dispatchState":"DONE","resultCount":999}}]}`))
+		case strings.HasPrefix(r.URL.Path, "/services/search/v2/jobs/"):
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"messages":[{"type":"FATAL","text":"Unknown sid."}]}`))
+		default:
+			offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+			end := offset + 2
+			if end > len(instances) {
+				end = len(instances)
+			}
+			_, _ = fmt.Fprintf(w, `{"paging": {"total": %d, "offset": %d}, "entry": [%s]}`, len(instances), offset, strings.Join(instances[offset:end], ","))
+		}
+	})
+	ctx := context.Background()
+
+	opts := &TriggeredAlertOptions{
+		SavedSearchName: "disk full",
+		MinSeverity:     SeverityWarn,
+		Since:           time.Unix(1700000000, 0),
+		Until:           time.Unix(1700086400, 0),
+		ReadResultCount: true,
+		ListOptions:     ListOptions{Count: 2},
+	}
+	page, err := ListTriggeredAlerts(ctx, client, opts)
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	// the instances are fetched until 2 of them match
+	if len(page.Alerts) != 2 || page.NextOffset != 4 || page.Alerts[1].Name != "scheduler__admin__search__RMD5disk_at_1700010800_4" {
+		t.Fatalf("Expected a page with 2 alerts but got %+v.", page)
+	}
+	alert := page.Alerts[0]
+	if alert.SavedSearchName != "disk full" || alert.Sid != "scheduler__admin__search__RMD5disk_at_1700000000_1" || alert.Severity != SeveritySevere ||
+		!alert.DigestMode || !alert.TriggerTime.Equal(time.Unix(1700000000, 0)) || alert.ExpirationTime != "2023-11-15 22:13:20 UTC" ||
+		alert.TriggeredAlerts != 1 || alert.ResultCount != 12 {
+		t.Fatalf("Expected the full content of the instance but got %+v.", alert)
+	}
+	if page.Alerts[1].ResultCount != -1 {
+		t.Fatalf("Expected no result count for an expired job but got %d.", page.Alerts[1].ResultCount)
+	}
+	if received := requests()[0]; received.URL.Path != "/services/alerts/fired_alerts/disk full" || received.URL.Query().Get("count") != "100" {
+		t.Fatalf("Expected the instances of the saved search but got %s.", received.URL)
+	}
+
+	// the job of an instance without sid is not looked up
+	before := len(requests())
+	unknown, err := ListTriggeredAlerts(ctx, client, &TriggeredAlertOptions{MinSeverity: SeverityFatal, ReadResultCount: true, ListOptions: ListOptions{Count: 1}})
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	if len(unknown.Alerts) != 1 || unknown.Alerts[0].Sid != "" || unknown.Alerts[0].ResultCount != -1 {
+		t.Fatalf("Expected no result count for an instance without sid but got %+v.", unknown.Alerts)
+	}
+	for _, received := range requests()[before:] {
+		if strings.HasPrefix(received.URL.Path, "/services/search/v2/jobs") {
+			t.Fatalf("Expected no request of the job but got %s.", received.URL.Path)
+		}
+	}
+
+	// the last page
+	opts.Offset = page.NextOffset
+	if page, err = ListTriggeredAlerts(ctx, client, opts); err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	if len(page.Alerts) != 0 || page.NextOffset != 0 {
+		t.Fatalf("Expected an empty last page but got %+v.", page)
+	}
+
+	// the instances of every saved search
+	if page, err = ListTriggeredAlerts(ctx, client, nil); err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	received := requests()
+	if last := received[len(received)-1]; last.URL.Path != "/services/alerts/fired_alerts/-" || last.URL.Query().Get("count") != "0" {
+		t.Fatalf("Expected every instance but got %s.", last.URL)
+	}
+
+	if err := DeleteTriggeredAlert(ctx, client, alert.Name); err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	received = requests()
+	if last := received[len(received)-1]; last.Method != http.MethodDelete || last.URL.Path != "/services/alerts/fired_alerts/"+alert.Name {
+		t.Fatalf("Expected a delete request of the instance but got %s %s.", last.Method, last.URL.Path)
+	}
+}
+
+func TestDispatchAlertRetry(t *testing.T) {
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 && r.URL.Path == "/services/saved/searches/refused/dispatch" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path == "/services/saved/searches/slow/dispatch" {
+			// longer than the timeout of the http client
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"sid":"1700000000.1"}`))
+	}))
+	defer server.Close()
+	serverUrl, _ := url.Parse(server.URL)
+
+	client, err := splunk.New(serverUrl.Hostname(),
+		splunk.WithPort(serverUrl.Port()),
+		splunk.WithScheme("http"),
+		splunk.WithToken(splunkTest.GetTestToken()),
+		splunk.WithHTTPClient(&http.Client{Timeout: 50 * time.Millisecond}),
+		splunk.WithRetry(splunk.RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+
+	// a dispatch refused by splunk is sent again
+	if sid, err := DispatchAlert(context.Background(), client, "refused", nil); err != nil || sid != "1700000000.1" {
+		t.Fatalf("Expected the SID of the job but got %s (%v).", sid, err)
+	}
+	if count := attempts.Swap(0); count != 2 {
+		t.Fatalf("Expected 2 attempts but got %d.", count)
+	}
+
+	// a timed out dispatch may have started a job, it is not sent again
+	if _, err := DispatchAlert(context.Background(), client, "slow", nil); err == nil {
+		t.Fatalf("Expected a timeout.")
+	}
+	if count := attempts.Load(); count != 1 {
+		t.Fatalf("Expected 1 attempt but got %d.", count)
+	}
+}
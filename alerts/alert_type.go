@@ -0,0 +1,168 @@
+package alerts
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// types of alert, the "number of results" alerts of the splunk UI being number of events alerts
+const (
+	AlertTypeAlways          = "always"
+	AlertTypeNumberOfEvents  = "number of events"
+	AlertTypeNumberOfHosts   = "number of hosts"
+	AlertTypeNumberOfSources = "number of sources"
+	AlertTypeCustom          = "custom"
+)
+
+// comparators of the number of events, hosts or sources with the threshold.
+// The perc comparators read the threshold as a percentage of the previous run, the others as a count
+const (
+	ComparatorGreaterThan = "greater than"
+	ComparatorLessThan    = "less than"
+	ComparatorEqualTo     = "equal to"
+	ComparatorNotEqualTo  = "not equal to"
+	ComparatorDropsBy     = "drops by"
+	ComparatorRisesBy     = "rises by"
+	ComparatorDropsByPerc = "drops by perc"
+	ComparatorRisesByPerc = "rises by perc"
+)
+
+// severities of a triggered alert
+const (
+	SeverityDebug  = 1
+	SeverityInfo   = 2
+	SeverityWarn   = 3
+	SeverityError  = 4
+	SeveritySevere = 5
+	SeverityFatal  = 6
+)
+
+// validateAlertType checks the type of the alert and the fields it requires
+func validateAlertType(p *AlertParams) error {
+
+	switch p.AlertType {
+	case "", AlertTypeAlways, AlertTypeCustom:
+		if p.AlertComparator != "" || p.AlertThreshold != "" {
+			return fmt.Errorf("the comparator and the threshold are only used by the number of events, hosts or sources alerts")
+		}
+	case AlertTypeNumberOfEvents, AlertTypeNumberOfHosts, AlertTypeNumberOfSources:
+		if err := validateThreshold(p.AlertComparator, p.AlertThreshold); err != nil {
+			return fmt.Errorf("%s alert : %w", p.AlertType, err)
+		}
+	default:
+		return fmt.Errorf("unsupported alert type %q", p.AlertType)
+	}
+
+	if p.AlertCondition != "" && p.AlertType != "" && p.AlertType != AlertTypeCustom {
+		return fmt.Errorf("the alert condition is only used by the custom alerts")
+	}
+	if p.AlertSeverity < 0 || p.AlertSeverity > SeverityFatal {
+		return fmt.Errorf("the alert severity must be between %d and %d, got %d", SeverityDebug, SeverityFatal, p.AlertSeverity)
+	}
+	if p.RealTimeWindow != "" && (p.EarliestTime != "" || p.LatestTime != "") {
+		return fmt.Errorf("the earliest and latest times of a real-time alert are given by its window")
+	}
+	return nil
+}
+
+// mergeAlertType returns the type of the alert once the request is applied on the attributes of the saved search
+func mergeAlertType(attributes Attributes, p AlertParams) AlertParams {
+
+	if p.AlertType == "" {
+		p.AlertType = attributes["alert_type"]
+	}
+	// splunk keeps the comparator and the threshold of the other types, they are only read by these ones
+	switch p.AlertType {
+	case AlertTypeNumberOfEvents, AlertTypeNumberOfHosts, AlertTypeNumberOfSources:
+		if p.AlertComparator == "" {
+			p.AlertComparator = attributes["alert_comparator"]
+		}
+		if p.AlertThreshold == "" {
+			p.AlertThreshold = attributes["alert_threshold"]
+		}
+	}
+	return p
+}
+
+func validateThreshold(comparator string, threshold string) error {
+
+	switch comparator {
+	case ComparatorGreaterThan, ComparatorLessThan, ComparatorEqualTo, ComparatorNotEqualTo, ComparatorDropsBy, ComparatorRisesBy,
+		ComparatorDropsByPerc, ComparatorRisesByPerc:
+	case "":
+		return fmt.Errorf("the comparator is required")
+	default:
+		return fmt.Errorf("unsupported comparator %q", comparator)
+	}
+
+	if threshold == "" {
+		return fmt.Errorf("the threshold is required")
+	}
+	if value, err := strconv.Atoi(thresholdValue(comparator, threshold)); err != nil || value < 0 {
+		return fmt.Errorf("invalid threshold %q, a positive integer is expected", threshold)
+	}
+	return nil
+}
+
+// thresholdValue returns the threshold sent to splunk, a percentage being given with or without its % sign
+func thresholdValue(comparator string, threshold string) string {
+
+	if comparator == ComparatorDropsByPerc || comparator == ComparatorRisesByPerc {
+		return strings.TrimSuffix(threshold, "%")
+	}
+	return threshold
+}
+
+// alertTypeParams returns the attributes of the type of the alert, with the defaults of the creation if create is true
+func alertTypeParams(p *AlertParams, create bool) url.Values {
+
+	params := url.Values{}
+	switch {
+	case p.AlertType != "":
+		params.Add("alert_type", p.AlertType)
+	case create:
+		params.Add("alert_type", AlertTypeCustom)
+	}
+	if p.AlertComparator != "" {
+		params.Add("alert_comparator", p.AlertComparator)
+	}
+	if p.AlertThreshold != "" {
+		params.Add("alert_threshold", thresholdValue(p.AlertComparator, p.AlertThreshold))
+	}
+	if p.AlertCondition != "" {
+		params.Add("alert_condition", p.AlertCondition)
+	}
+	if p.AlertSeverity != 0 {
+		params.Add("alert.severity", strconv.Itoa(p.AlertSeverity))
+	}
+	if p.AlertDigestMode != nil {
+		params.Add("alert.digest_mode", formatBool(*p.AlertDigestMode))
+	}
+	switch {
+	case p.AlertTrack != nil:
+		params.Add("alert.track", formatBool(*p.AlertTrack))
+	case create:
+		params.Add("alert.track", "1")
+	}
+	if p.RealTimeWindow != "" {
+		params.Add("dispatch.earliest_time", "rt-"+p.RealTimeWindow)
+		params.Add("dispatch.latest_time", "rt")
+	}
+	return params
+}
+
+// Bool returns a pointer to the boolean, for the optional fields of AlertParams
+func Bool(b bool) *bool {
+
+	return &b
+}
+
+func formatBool(b bool) string {
+
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,383 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	splunk "github.com/kuro-jojo/splunk-sdk-go/client"
+	utils "github.com/kuro-jojo/splunk-sdk-go/pkg/utils"
+)
+
+// Attributes holds the content of an entry returned by splunk, every value being kept as a string
+type Attributes map[string]string
+
+// UnmarshalJSON decodes the content whatever the json type of its values : numbers and booleans keep their json representation,
+// lists are joined with commas and null values are skipped
+func (a *Attributes) UnmarshalJSON(data []byte) error {
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	attributes := make(Attributes, len(fields))
+	for name, raw := range fields {
+		var decoded interface{}
+		if err := json.Unmarshal(raw, &decoded); err != nil {
+			return fmt.Errorf("attribute %s : %w", name, err)
+		}
+		switch v := decoded.(type) {
+		case nil:
+			continue
+		case string:
+			attributes[name] = v
+		case []interface{}:
+			values := make([]string, 0, len(v))
+			for _, value := range v {
+				values = append(values, fmt.Sprint(value))
+			}
+			attributes[name] = strings.Join(values, ",")
+		default:
+			attributes[name] = strings.TrimSpace(string(raw))
+		}
+	}
+
+	*a = attributes
+	return nil
+}
+
+// Bool returns the attribute as a boolean, splunk using either true/false or 1/0
+func (a Attributes) Bool(name string) bool {
+
+	b, _ := parseBool(a[name])
+	return b
+}
+
+// Int returns the attribute as an integer, 0 if it has none
+func (a Attributes) Int(name string) int {
+
+	i, _ := strconv.Atoi(a[name])
+	return i
+}
+
+// ACL tells who owns an entry and where it is shared
+type ACL struct {
+	Owner   string `json:"owner"`
+	App     string `json:"app"`
+	Sharing string `json:"sharing"`
+}
+
+// SavedSearch is the configuration of a saved search, an alert being a scheduled saved search with a trigger condition.
+// The typed fields are read from Attributes, which holds every attribute returned by splunk
+type SavedSearch struct {
+	Name string
+	ACL  ACL
+
+	Search               string
+	Description          string
+	Disabled             bool
+	IsScheduled          bool
+	CronSchedule         string
+	NextScheduledTime    string
+	DispatchEarliestTime string
+	DispatchLatestTime   string
+
+	AlertType           string
+	AlertComparator     string
+	AlertThreshold      string
+	AlertCondition      string
+	AlertSeverity       int
+	AlertDigestMode     bool
+	AlertTrack          bool
+	AlertExpires        string
+	AlertSuppress       bool
+	AlertSuppressPeriod string
+	AlertSuppressFields string
+	// comma separated names of the actions run when the alert is triggered
+	Actions string
+
+	Attributes Attributes
+}
+
+// savedSearchEntries is the response of the saved/searches endpoints
+type savedSearchEntries struct {
+	Entry []struct {
+		Name    string     `json:"name"`
+		ACL     ACL        `json:"acl"`
+		Content Attributes `json:"content"`
+	} `json:"entry"`
+}
+
+func (entries savedSearchEntries) savedSearches() []SavedSearch {
+
+	savedSearches := make([]SavedSearch, 0, len(entries.Entry))
+	for _, entry := range entries.Entry {
+		content := entry.Content
+		savedSearches = append(savedSearches, SavedSearch{
+			Name:                 entry.Name,
+			ACL:                  entry.ACL,
+			Search:               content["search"],
+			Description:          content["description"],
+			Disabled:             content.Bool("disabled"),
+			IsScheduled:          content.Bool("is_scheduled"),
+			CronSchedule:         content["cron_schedule"],
+			NextScheduledTime:    content["next_scheduled_time"],
+			DispatchEarliestTime: content["dispatch.earliest_time"],
+			DispatchLatestTime:   content["dispatch.latest_time"],
+			AlertType:            content["alert_type"],
+			AlertComparator:      content["alert_comparator"],
+			AlertThreshold:       content["alert_threshold"],
+			AlertCondition:       content["alert_condition"],
+			AlertSeverity:        content.Int("alert.severity"),
+			AlertDigestMode:      content.Bool("alert.digest_mode"),
+			AlertTrack:           content.Bool("alert.track"),
+			AlertExpires:         content["alert.expires"],
+			AlertSuppress:        content.Bool("alert.suppress"),
+			AlertSuppressPeriod:  content["alert.suppress.period"],
+			AlertSuppressFields:  content["alert.suppress.fields"],
+			Actions:              content["actions"],
+			Attributes:           content,
+		})
+	}
+	return savedSearches
+}
+
+// ListOptions selects and orders the entries of a list
+type ListOptions struct {
+	// filter on the entries, such as "name=disk*" or a plain text matched against every field
+	Search string
+	// maximum number of entries to return, 0 returns all of them
+	Count int
+	// index of the first entry to return
+	Offset int
+	// field used to sort the entries and direction of the sort : asc (default) or desc
+	SortKey string
+	SortDir string
+}
+
+// values returns the query parameters of the options
+func (opts *ListOptions) values() url.Values {
+
+	params := url.Values{}
+	params.Add("output_mode", "json")
+	if opts == nil {
+		params.Add("count", "0")
+		return params
+	}
+
+	params.Add("count", strconv.Itoa(opts.Count))
+	if opts.Offset > 0 {
+		params.Add("offset", strconv.Itoa(opts.Offset))
+	}
+	if opts.Search != "" {
+		params.Add("search", opts.Search)
+	}
+	if opts.SortKey != "" {
+		params.Add("sort_key", opts.SortKey)
+	}
+	if opts.SortDir != "" {
+		params.Add("sort_dir", opts.SortDir)
+	}
+	return params
+}
+
+// GetAlert returns the full configuration of the saved search
+func GetAlert(ctx context.Context, client *splunk.SplunkClient, alertName string) (*SavedSearch, error) {
+
+	params := url.Values{}
+	params.Add("output_mode", "json")
+
+	savedSearches, err := getSavedSearches(splunk.ContextWithOperation(ctx, OperationGetAlert), client, savedSearchesPath+alertName, params)
+	if err != nil {
+		return nil, fmt.Errorf("alert %s : %w", alertName, err)
+	}
+	if len(savedSearches) == 0 {
+		return nil, fmt.Errorf("alert %s : %w", alertName, splunk.ErrNotFound)
+	}
+	return &savedSearches[0], nil
+}
+
+// ListAlerts returns the saved searches selected by opts with their full configuration, nil opts returning all of them
+func ListAlerts(ctx context.Context, client *splunk.SplunkClient, opts *ListOptions) ([]SavedSearch, error) {
+
+	savedSearches, err := getSavedSearches(splunk.ContextWithOperation(ctx, OperationListAlerts), client, savedSearchesPath, opts.values())
+	if err != nil {
+		return nil, fmt.Errorf("alerts listing : %w", err)
+	}
+	return savedSearches, nil
+}
+
+// UpdateAlert changes the saved search in place, so its history and its permissions are kept.
+// Only the attributes set in the request which differ from the current configuration are sent, the name of the request is ignored
+func UpdateAlert(ctx context.Context, client *splunk.SplunkClient, alertName string, spAlert *AlertRequest) error {
+
+	current, err := GetAlert(ctx, client, alertName)
+	if err != nil {
+		return fmt.Errorf("alert update : %w", err)
+	}
+
+	// work on a copy so that the request of the caller is left untouched
+	alert := *spAlert
+	alert.Params.Name = ""
+	alert.Params.SearchQuery = utils.ValidateAlertQuery(alert.Params.SearchQuery)
+
+	// the fields left empty keep the type of the saved search
+	merged := mergeAlertType(current.Attributes, alert.Params)
+	if err := validateAlertType(&merged); err != nil {
+		return fmt.Errorf("alert update : %w", err)
+	}
+	// a percentage is read with the comparator of the saved search when the request has none
+	alert.Params.AlertThreshold = thresholdValue(merged.AlertComparator, alert.Params.AlertThreshold)
+
+	// the defaults of the creation are not applied to an existing saved search
+	params, err := alertParams(&alert, false)
+	if err != nil {
+		return fmt.Errorf("alert update : %w", err)
+	}
+	params = changedAttributes(current.Attributes, params)
+	if len(params) == 0 {
+		return nil
+	}
+	params.Add("output_mode", "json")
+
+	endpoint := utils.CreateEndpoint(client, savedSearchesPath+alertName)
+	resp, err := splunk.MakeHttpRequestWithContext(splunk.ContextWithOperation(ctx, OperationUpdateAlert), client, http.MethodPost, endpoint, alert.Headers, params)
+	if err != nil {
+		return fmt.Errorf("alert update : error while making the post request : %w", err)
+	}
+	defer resp.Body.Close()
+
+	// handle error
+	if err := splunk.CheckResponse(resp); err != nil {
+		return fmt.Errorf("alert update : %w", err)
+	}
+	return nil
+}
+
+// EnableAlert schedules the saved search again after DisableAlert
+func EnableAlert(ctx context.Context, client *splunk.SplunkClient, alertName string) error {
+
+	// enabling twice has the same effect
+	ctx = splunk.ContextWithRetrySafe(splunk.ContextWithOperation(ctx, OperationEnableAlert))
+	if _, err := postSavedSearch(ctx, client, savedSearchesPath+alertName+"/enable", nil); err != nil {
+		return fmt.Errorf("alert %s : enabling : %w", alertName, err)
+	}
+	return nil
+}
+
+// DisableAlert stops the scheduling of the saved search, which keeps its configuration and its history
+func DisableAlert(ctx context.Context, client *splunk.SplunkClient, alertName string) error {
+
+	// disabling twice has the same effect
+	ctx = splunk.ContextWithRetrySafe(splunk.ContextWithOperation(ctx, OperationDisableAlert))
+	if _, err := postSavedSearch(ctx, client, savedSearchesPath+alertName+"/disable", nil); err != nil {
+		return fmt.Errorf("alert %s : disabling : %w", alertName, err)
+	}
+	return nil
+}
+
+// postSavedSearch makes a post request to a saved/searches endpoint and returns the body of the response
+func postSavedSearch(ctx context.Context, client *splunk.SplunkClient, service string, params url.Values) ([]byte, error) {
+
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("output_mode", "json")
+
+	endpoint := utils.CreateEndpoint(client, service)
+	headers := map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
+
+	resp, err := splunk.MakeHttpRequestWithContext(ctx, client, http.MethodPost, endpoint, headers, params)
+	if err != nil {
+		return nil, fmt.Errorf("error while making the post request : %w", err)
+	}
+	defer resp.Body.Close()
+
+	// handle error
+	if err := splunk.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error while getting the body of the post request : %w", err)
+	}
+	return body, nil
+}
+
+// changedAttributes returns the parameters whose value differs from the current attribute
+func changedAttributes(current Attributes, params url.Values) url.Values {
+
+	changed := url.Values{}
+	for name := range params {
+		value := params.Get(name)
+		if currentValue, ok := current[name]; !ok || !sameValue(currentValue, value) {
+			changed.Set(name, value)
+		}
+	}
+	return changed
+}
+
+// sameValue compares two values of an attribute, splunk writing booleans and numbers in several ways
+func sameValue(a string, b string) bool {
+
+	if a == b {
+		return true
+	}
+	if boolA, err := parseBool(a); err == nil {
+		if boolB, err := parseBool(b); err == nil {
+			return boolA == boolB
+		}
+	}
+	if floatA, err := strconv.ParseFloat(a, 64); err == nil {
+		if floatB, err := strconv.ParseFloat(b, 64); err == nil {
+			return floatA == floatB
+		}
+	}
+	return false
+}
+
+func parseBool(value string) (bool, error) {
+
+	switch strings.ToLower(value) {
+	case "1", "true":
+		return true, nil
+	case "0", "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q is not a boolean", value)
+}
+
+// getSavedSearches makes a get request to a saved/searches endpoint and decodes its entries
+func getSavedSearches(ctx context.Context, client *splunk.SplunkClient, service string, params url.Values) ([]SavedSearch, error) {
+
+	endpoint := utils.CreateEndpoint(client, service)
+
+	resp, err := splunk.MakeHttpRequestWithContext(ctx, client, http.MethodGet, endpoint, nil, params)
+	if err != nil {
+		return nil, fmt.Errorf("error while making the get request : %w", err)
+	}
+	defer resp.Body.Close()
+
+	// handle error
+	if err := splunk.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error while getting the body of the get request : %w", err)
+	}
+
+	var entries savedSearchEntries
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, fmt.Errorf("could not map list of alerts to datastructure: %w", err)
+	}
+	return entries.savedSearches(), nil
+}
@@ -2,30 +2,54 @@ package client
 
 import (
 	"crypto/tls"
+	"log/slog"
 	"net/http"
 )
 
+// SplunkClient holds the connection settings of a Splunk instance.
+// It is never modified by the SDK once created, so a single client can be shared between goroutines
 type SplunkClient struct {
 	Client     *http.Client
 	Host       string
 	Port       string
-	Endpoint   string
 	Token      string
 	Username   string
 	Password   string
 	SessionKey string
 	// if true, ssl verification is skipped
 	SkipSSL bool
+	// scheme of the urls, https if empty
+	Scheme string
+	// path prefix under which splunk is served, if any
+	BasePath  string
+	UserAgent string
+	// namespace of the requests : the user and the app owning the knowledge objects.
+	// If one of them is set, the services are reached through servicesNS/{owner}/{app}/, an empty one standing for the wildcard "-"
+	Owner string
+	App   string
+	// set when the client logs in with its username and password to get a session key
+	session *session
+	// retries of the failed requests, none if nil
+	retry *RetryPolicy
+	// slots of the concurrent searches, unlimited if nil
+	searchSlots chan struct{}
+	// limit of the rate of the requests, none if nil
+	rateLimiter *rateLimiter
+	// middlewares wrapping every request, the first one being the outermost
+	middlewares []Middleware
+	// logger of the requests, the SDK is silent if nil
+	logger *slog.Logger
+	// tracer and instruments of the client, nothing is recorded if nil
+	tel *telemetry
 }
 
+// NamespaceWildcard matches every user or every app of the namespace
+const NamespaceWildcard = "-"
+
 // create a new Client
+// see New for a client configured with options. skipSSL only applies to an *http.Transport, any other transport is kept as it is
 func NewClient(client *http.Client, host string, port string, token string, username string, password string, sessionKey string, skipSSL bool) *SplunkClient {
-	if skipSSL {
-		tr := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		}
-		client.Transport = tr
-	}
+	client = skipSSLVerification(client, skipSSL)
 
 	return &SplunkClient{
 		Client:     client,
@@ -41,12 +65,7 @@ func NewClient(client *http.Client, host string, port string, token string, user
 
 // create a new client that could connect with authentication tokens
 func NewClientAuthenticatedByToken(client *http.Client, host string, port string, token string, skipSSL bool) *SplunkClient {
-	if skipSSL {
-		tr := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		}
-		client.Transport = tr
-	}
+	client = skipSSLVerification(client, skipSSL)
 
 	return &SplunkClient{
 		Client:     client,
@@ -62,12 +81,7 @@ func NewClientAuthenticatedByToken(client *http.Client, host string, port string
 
 // create a new client that could connect with authentication sessionKey
 func NewClientAuthenticatedBySessionKey(client *http.Client, host string, port string, sessionKey string, skipSSL bool) *SplunkClient {
-	if skipSSL {
-		tr := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		}
-		client.Transport = tr
-	}
+	client = skipSSLVerification(client, skipSSL)
 
 	return &SplunkClient{
 		Client:     client,
@@ -83,12 +97,7 @@ func NewClientAuthenticatedBySessionKey(client *http.Client, host string, port s
 
 // create a new client with basic authentication method
 func NewBasicAuthenticatedClient(client *http.Client, host string, port string, username string, password string, skipSSL bool) *SplunkClient {
-	if skipSSL {
-		tr := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		}
-		client.Transport = tr
-	}
+	client = skipSSLVerification(client, skipSSL)
 
 	return &SplunkClient{
 		Client:     client,
@@ -101,3 +110,43 @@ func NewBasicAuthenticatedClient(client *http.Client, host string, port string,
 		SkipSSL:    skipSSL,
 	}
 }
+
+// create a new client that logs in with its username and password and then authenticates with the session key it got.
+// When the session expires, the client logs in again and retries the request
+func NewSessionAuthenticatedClient(client *http.Client, host string, port string, username string, password string, skipSSL bool) *SplunkClient {
+	splunkClient := NewBasicAuthenticatedClient(client, host, port, username, password, skipSSL)
+	splunkClient.session = &session{}
+
+	return splunkClient
+}
+
+// skipSSLVerification returns a copy of the http client whose transport skips the verification of certificates.
+// The settings of the transport of the caller are kept when it is an *http.Transport. Any other transport, such as an
+// instrumentation wrapper, is used as it is : skipSSL cannot be layered on it, its own TLS configuration must skip the verification
+func skipSSLVerification(client *http.Client, skipSSL bool) *http.Client {
+	if !skipSSL {
+		return client
+	}
+
+	transport, err := withTLSConfig(client.Transport, func(config *tls.Config) {
+		config.InsecureSkipVerify = true
+	})
+	if err != nil {
+		return client
+	}
+
+	skipSSLClient := *client
+	skipSSLClient.Transport = transport
+	return &skipSSLClient
+}
+
+// Namespaced returns a copy of the client whose requests are made in the namespace of the owner and the app.
+// The copy shares the http client and the session of the client
+//
+//	alerts.CreateAlert(client.Namespaced("nobody", "search"), alertRequest)
+func (client *SplunkClient) Namespaced(owner string, app string) *SplunkClient {
+
+	namespaced := *client
+	namespaced.Owner, namespaced.App = owner, app
+	return &namespaced
+}
@@ -0,0 +1,790 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCheckResponse(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"messages":[{"type":"ERROR","text":"An object with name=my-alert already exists"},{"type":"WARN","text":"second message"}]}`))
+	}))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/services/saved/searches/?output_mode=json", "application/x-www-form-urlencoded", nil)
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	defer resp.Body.Close()
+
+	err = CheckResponse(resp)
+
+	var splunkErr *SplunkError
+	if !errors.As(err, &splunkErr) {
+		t.Fatalf("Expected a *SplunkError but got %v.", err)
+	}
+	if !errors.Is(err, ErrConflict) || errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected the error to only match %v but got %v.", ErrConflict, err)
+	}
+	if splunkErr.Method != http.MethodPost || splunkErr.Endpoint != server.URL+"/services/saved/searches/" {
+		t.Fatalf("Expected the method and the endpoint of the request but got %s %s.", splunkErr.Method, splunkErr.Endpoint)
+	}
+	if len(splunkErr.Messages) != 2 || splunkErr.Messages[1].Type != MessageTypeWarn || !splunkErr.HasMessageType(MessageTypeError) {
+		t.Fatalf("Expected every message with its type but got %v.", splunkErr.Messages)
+	}
+	if !strings.Contains(err.Error(), "already exists") || !strings.Contains(err.Error(), "second message") {
+		t.Fatalf("Expected every message in %q.", err)
+	}
+}
+
+func TestHandleHttpError(t *testing.T) {
+
+	status, err := HandleHttpError([]byte(`{"messages":[{"type":"ERROR","text":"Unknown sid."}]}`))
+	if err != nil || status != "Unknown sid." {
+		t.Fatalf("Expected %q but got %q (%v).", "Unknown sid.", status, err)
+	}
+
+	if _, err := HandleHttpError([]byte(`{"entry":[]}`)); err == nil {
+		t.Fatalf("Expected an error for a body without messages.")
+	}
+}
+
+func TestSessionAuthentication(t *testing.T) {
+
+	var mu sync.Mutex
+	logins := 0
+	validKey := ""
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Path == "/services/auth/login" {
+			if r.FormValue("username") != "admin" || r.FormValue("password") != "changeme" {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"messages":[{"type":"WARN","text":"Login failed"}]}`))
+				return
+			}
+			logins++
+			validKey = fmt.Sprintf("key-%d", logins)
+			_, _ = fmt.Fprintf(w, `{"sessionKey":"%s"}`, validKey)
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Splunk "+validKey {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"messages":[{"type":"WARN","text":"call not properly authenticated"}]}`))
+			return
+		}
+		// the session expires after each request on this endpoint
+		if r.URL.Path == "/services/expire" {
+			validKey = "expired"
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	serverUrl, _ := url.Parse(server.URL)
+	client := NewSessionAuthenticatedClient(&http.Client{}, serverUrl.Hostname(), serverUrl.Port(), "admin", "changeme", true)
+
+	if err := Login(context.Background(), client); err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+
+	// every request is made with the session key, the expired one is renewed once for all the goroutines
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			service := "services/search/v2/jobs/"
+			if i == 0 {
+				service = "services/expire"
+			}
+			resp, err := MakeHttpRequest(client, http.MethodGet, CreateEndpoint(client, service), nil, nil)
+			if err != nil {
+				t.Errorf("Got an error : %s", err)
+				return
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("Expected %v but got %v.", http.StatusOK, resp.Status)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// a namespaced copy shares the session of the client
+	namespaced := client.Namespaced("nobody", "search")
+	resp, err := MakeHttpRequest(namespaced, http.MethodGet, CreateEndpoint(namespaced, "services/search/v2/jobs/"), nil, nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected %v but got %v (%v).", http.StatusOK, resp, err)
+	}
+	resp.Body.Close()
+
+	mu.Lock()
+	if logins != 2 || client.SessionKey != "" {
+		t.Fatalf("Expected 2 logins and an untouched client but got %d logins.", logins)
+	}
+	mu.Unlock()
+
+	wrongClient := NewSessionAuthenticatedClient(&http.Client{}, serverUrl.Hostname(), serverUrl.Port(), "admin", "wrong", true)
+	if err := Login(context.Background(), wrongClient); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("Expected %v but got %v.", ErrUnauthorized, err)
+	}
+}
+
+func TestSessionLoginWait(t *testing.T) {
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	logins := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/services/auth/login" {
+			mu.Lock()
+			logins++
+			mu.Unlock()
+			<-release
+			_, _ = w.Write([]byte(`{"sessionKey":"key"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	serverUrl, _ := url.Parse(server.URL)
+	client := NewSessionAuthenticatedClient(&http.Client{}, serverUrl.Hostname(), serverUrl.Port(), "admin", "changeme", true)
+	endpoint := CreateEndpoint(client, "services/search/v2/jobs/")
+
+	// the first request logs in for the others
+	first := make(chan error, 1)
+	go func() {
+		resp, err := MakeHttpRequest(client, http.MethodGet, endpoint, nil, nil)
+		if err == nil {
+			resp.Body.Close()
+		}
+		first <- err
+	}()
+	for {
+		mu.Lock()
+		started := logins == 1
+		mu.Unlock()
+		if started {
+			break
+		}
+		select {
+		case err := <-first:
+			t.Fatalf("Expected the first request to wait for the login but got %v.", err)
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	// a request waiting for the login still gives up with its context
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := MakeHttpRequestWithContext(ctx, client, http.MethodGet, endpoint, nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected %v but got %v.", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Expected the request to give up with its context but it waited %s.", elapsed)
+	}
+
+	close(release)
+	if err := <-first; err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if logins != 1 {
+		t.Fatalf("Expected 1 login but got %d.", logins)
+	}
+}
+
+type recordingTransport struct {
+	requests []*http.Request
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.requests = append(rt.requests, req)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestNew(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/splunk/services/search/v2/jobs/" || r.Header.Get("User-Agent") != "my-agent/1.0" || r.Header.Get("Authorization") != "Bearer my-token" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	serverUrl, _ := url.Parse(server.URL)
+
+	transport := &recordingTransport{}
+	client, err := New(serverUrl.Hostname(),
+		WithPort(serverUrl.Port()),
+		WithToken("my-token"),
+		WithScheme("http"),
+		WithBasePath("/splunk/"),
+		WithUserAgent("my-agent/1.0"),
+		WithTransport(transport),
+	)
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+
+	resp, err := MakeHttpRequest(client, http.MethodGet, CreateEndpoint(client, "services/search/v2/jobs/"), nil, nil)
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(transport.requests) != 1 {
+		t.Fatalf("Expected a request through the given transport but got %v.", resp.Status)
+	}
+
+	// TLS options need a transport they can configure
+	if _, err := New("localhost", WithToken("my-token"), WithTransport(transport), WithInsecureSkipVerify()); err == nil {
+		t.Fatalf("Expected an error for a transport which is not an *http.Transport.")
+	}
+
+	if _, err := New("localhost", WithToken("my-token"), WithNamespace("", "search")); err == nil {
+		t.Fatalf("Expected an error for an empty owner.")
+	}
+
+	if _, err := New("localhost", WithToken("my-token"), WithBasicAuth("admin", "changeme")); err == nil {
+		t.Fatalf("Expected an error for several authentication methods.")
+	}
+}
+
+func TestSkipSSLKeepsTransport(t *testing.T) {
+
+	proxy := func(*http.Request) (*url.URL, error) { return nil, nil }
+	httpClient := &http.Client{Transport: &http.Transport{Proxy: proxy}}
+
+	for _, client := range []*SplunkClient{
+		NewClientAuthenticatedByToken(httpClient, "localhost", "8089", "my-token", true),
+		mustNew(t, "localhost", WithToken("my-token"), WithHTTPClient(httpClient), WithInsecureSkipVerify()),
+	} {
+		transport, ok := client.Client.Transport.(*http.Transport)
+		if !ok || transport.Proxy == nil || !transport.TLSClientConfig.InsecureSkipVerify {
+			t.Fatalf("Expected the transport of the caller with ssl verification skipped but got %+v.", client.Client.Transport)
+		}
+	}
+
+	if config := httpClient.Transport.(*http.Transport).TLSClientConfig; config != nil && config.InsecureSkipVerify {
+		t.Fatalf("Expected the transport of the caller to be left untouched.")
+	}
+
+	// a wrapping transport is not replaced
+	wrapper := &recordingTransport{}
+	client := NewClientAuthenticatedByToken(&http.Client{Transport: wrapper}, "localhost", "8089", "my-token", true)
+	if client.Client.Transport != wrapper {
+		t.Fatalf("Expected the transport of the caller but got %T.", client.Client.Transport)
+	}
+}
+
+func mustNew(t *testing.T, host string, opts ...Option) *SplunkClient {
+
+	client, err := New(host, opts...)
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	return client
+}
+
+func TestTLSOptions(t *testing.T) {
+
+	clientCertPEM, clientKeyPEM := generateCertificate(t)
+	clientPool := x509.NewCertPool()
+	clientPool.AppendCertsFromPEM(clientCertPEM)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientPool,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	serverUrl, _ := url.Parse(server.URL)
+	serverCAPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	serverFingerprint := sha256.Sum256(server.Certificate().Raw)
+
+	for _, test := range []struct {
+		name      string
+		opts      []Option
+		expectErr bool
+	}{
+		{
+			name: "CA bundle and client certificate",
+			opts: []Option{WithCACertificates(serverCAPEM), WithClientCertificate(clientCertPEM, clientKeyPEM)},
+		},
+		{
+			name: "server name and pinned certificate",
+			opts: []Option{WithCACertificates(serverCAPEM), WithServerName("example.com"), WithClientCertificate(clientCertPEM, clientKeyPEM), WithPinnedCertificate(hex.EncodeToString(serverFingerprint[:]))},
+		},
+		{
+			name:      "no client certificate",
+			opts:      []Option{WithCACertificates(serverCAPEM)},
+			expectErr: true,
+		},
+		{
+			name:      "unknown CA",
+			opts:      []Option{WithClientCertificate(clientCertPEM, clientKeyPEM)},
+			expectErr: true,
+		},
+		{
+			name:      "wrong pinned certificate",
+			opts:      []Option{WithInsecureSkipVerify(), WithClientCertificate(clientCertPEM, clientKeyPEM), WithPinnedCertificate(strings.Repeat("ab:", 31) + "ab")},
+			expectErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			opts := append([]Option{WithPort(serverUrl.Port()), WithToken("my-token")}, test.opts...)
+			client := mustNew(t, serverUrl.Hostname(), opts...)
+
+			resp, err := MakeHttpRequest(client, http.MethodGet, CreateEndpoint(client, "services/server/info"), nil, nil)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != test.expectErr {
+				t.Fatalf("Expected an error : %v but got %v.", test.expectErr, err)
+			}
+		})
+	}
+
+	if _, err := New("localhost", WithToken("my-token"), WithCACertificates([]byte("not a certificate"))); err == nil {
+		t.Fatalf("Expected an error for an empty CA bundle.")
+	}
+}
+
+// generateCertificate returns a self-signed certificate and its key, PEM encoded
+func generateCertificate(t *testing.T) ([]byte, []byte) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "splunk-sdk-go"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func TestCreateEndpoint(t *testing.T) {
+
+	for _, test := range []struct {
+		client   SplunkClient
+		service  string
+		expected string
+	}{
+		{SplunkClient{Host: "localhost", Port: "8089"}, "services/search/v2/jobs/", "https://localhost:8089/services/search/v2/jobs/"},
+		{SplunkClient{Host: "https://localhost", Port: "8089"}, "services/search/v2/jobs/", "https://localhost:8089/services/search/v2/jobs/"},
+		{SplunkClient{Host: "http://proxy.local:8000/splunk/", Port: "8089"}, "services/saved/searches/", "http://proxy.local:8000/splunk/services/saved/searches/"},
+		{SplunkClient{Host: "http://proxy.local/splunk"}, "services/saved/searches/", "http://proxy.local/splunk/services/saved/searches/"},
+		{SplunkClient{Host: "proxy.local", Port: "80", Scheme: "http", BasePath: "/splunk"}, "services/saved/searches/", "http://proxy.local:80/splunk/services/saved/searches/"},
+		{SplunkClient{Host: "localhost:8090", Port: "8089"}, "services/saved/searches/", "https://localhost:8090/services/saved/searches/"},
+		{SplunkClient{Host: "::1", Port: "8089"}, "services/saved/searches/", "https://[::1]:8089/services/saved/searches/"},
+		{SplunkClient{Host: "localhost", Port: "8089"}, "services/saved/searches/My alert/50%", "https://localhost:8089/services/saved/searches/My%20alert/50%25"},
+		{SplunkClient{Host: "localhost", Port: "8089", Owner: "nobody", App: "search"}, "services/saved/searches/", "https://localhost:8089/servicesNS/nobody/search/saved/searches/"},
+		{SplunkClient{Host: "localhost", Port: "8089", App: "my app"}, "services/saved/searches/", "https://localhost:8089/servicesNS/-/my%20app/saved/searches/"},
+		{SplunkClient{Host: "localhost", Port: "8089", Owner: "admin", App: "search"}, "/servicesNS/nobody/search/alerts/fired_alerts/my_alert", "https://localhost:8089/servicesNS/nobody/search/alerts/fired_alerts/my_alert"},
+	} {
+		if endpoint := CreateEndpoint(&test.client, test.service); endpoint != test.expected {
+			t.Errorf("Expected %v but got %v.", test.expected, endpoint)
+		}
+	}
+
+	// the clients created by New use the port 8089 only for a bare host
+	for _, test := range []struct {
+		host     string
+		opts     []Option
+		expected string
+	}{
+		{"localhost", nil, "https://localhost:8089/services/search/v2/jobs/"},
+		{"localhost:8090", nil, "https://localhost:8090/services/search/v2/jobs/"},
+		{"http://proxy.local/splunk", nil, "http://proxy.local/splunk/services/search/v2/jobs/"},
+		{"https://splunk.example.com", nil, "https://splunk.example.com/services/search/v2/jobs/"},
+		{"https://splunk.example.com", []Option{WithPort("8089")}, "https://splunk.example.com:8089/services/search/v2/jobs/"},
+		{"http://proxy.local:8000/splunk", []Option{WithPort("8089")}, "http://proxy.local:8000/splunk/services/search/v2/jobs/"},
+	} {
+		client := mustNew(t, test.host, append(test.opts, WithToken("my-token"))...)
+		if endpoint := CreateEndpoint(client, "services/search/v2/jobs/"); endpoint != test.expected {
+			t.Errorf("%s : expected %v but got %v.", test.host, test.expected, endpoint)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+
+	var mu sync.Mutex
+	attempts := 0
+	failures := 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		attempts++
+		if attempts <= failures {
+			w.Header().Set("Retry-After", r.URL.Query().Get("retry_after"))
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"messages":[{"type":"ERROR","text":"Search not executed: too many concurrent searches"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	serverUrl, _ := url.Parse(server.URL)
+
+	client := mustNew(t, serverUrl.Hostname(),
+		WithPort(serverUrl.Port()),
+		WithScheme("http"),
+		WithToken("my-token"),
+		WithRetry(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}),
+	)
+	endpoint := CreateEndpoint(client, "services/search/v2/jobs/")
+
+	for _, test := range []struct {
+		name             string
+		ctx              context.Context
+		method           string
+		failures         int
+		expectedStatus   int
+		expectedAttempts int
+	}{
+		{"idempotent request retried", context.Background(), http.MethodGet, 2, http.StatusOK, 3},
+		{"attempts exhausted", context.Background(), http.MethodGet, 5, http.StatusServiceUnavailable, 3},
+		{"unsafe request not retried", context.Background(), http.MethodPost, 2, http.StatusServiceUnavailable, 1},
+		{"safe request retried", ContextWithRetrySafe(context.Background()), http.MethodPost, 2, http.StatusOK, 3},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			mu.Lock()
+			attempts, failures = 0, test.failures
+			mu.Unlock()
+
+			resp, err := MakeHttpRequestWithContext(test.ctx, client, test.method, endpoint, nil, nil)
+			if err != nil {
+				t.Fatalf("Got an error : %s", err)
+			}
+			resp.Body.Close()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if resp.StatusCode != test.expectedStatus || attempts != test.expectedAttempts {
+				t.Fatalf("Expected %v after %d attempts but got %v after %d attempts.", test.expectedStatus, test.expectedAttempts, resp.Status, attempts)
+			}
+		})
+	}
+
+	// the wait asked by splunk is interrupted by the cancellation of the context
+	mu.Lock()
+	attempts, failures = 0, 2
+	mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := MakeHttpRequestWithContext(ctx, client, http.MethodGet, endpoint, nil, url.Values{"retry_after": {"60"}})
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 5*time.Second {
+		t.Fatalf("Expected %v but got %v.", context.DeadlineExceeded, err)
+	}
+
+	policy := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 4 * time.Second, Jitter: -1}
+	for attempt, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		if wait := policy.backoff(attempt + 1); wait != expected {
+			t.Errorf("Expected %v but got %v.", expected, wait)
+		}
+	}
+	if wait, ok := parseRetryAfter("120"); !ok || wait != 2*time.Minute {
+		t.Errorf("Expected %v but got %v.", 2*time.Minute, wait)
+	}
+	if wait, ok := parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)); !ok || wait < 59*time.Minute {
+		t.Errorf("Expected about an hour but got %v.", wait)
+	}
+}
+
+func TestRetryErrors(t *testing.T) {
+
+	var mu sync.Mutex
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		if status, err := strconv.Atoi(r.FormValue("status")); err == nil {
+			w.WriteHeader(status)
+			return
+		}
+		// longer than the timeout of the http client
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+	serverUrl, _ := url.Parse(server.URL)
+
+	newClient := func(token string) *SplunkClient {
+		return mustNew(t, serverUrl.Hostname(),
+			WithPort(serverUrl.Port()),
+			WithScheme("http"),
+			WithToken(token),
+			WithHTTPClient(&http.Client{Timeout: 50 * time.Millisecond}),
+			WithRetry(RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Millisecond}),
+		)
+	}
+	client := newClient("my-token")
+	endpoint := CreateEndpoint(client, "services/search/v2/jobs/")
+
+	for _, test := range []struct {
+		name             string
+		ctx              context.Context
+		method           string
+		status           string
+		expectedAttempts int
+	}{
+		{"timeout of an idempotent request retried", context.Background(), http.MethodGet, "", 4},
+		{"timeout of a safe request retried", ContextWithRetrySafe(context.Background()), http.MethodPost, "", 4},
+		{"timeout of a search creation not retried", ContextWithRetryOnRefusal(context.Background()), http.MethodPost, "", 1},
+		{"refusal of a search creation retried", ContextWithRetryOnRefusal(context.Background()), http.MethodPost, "429", 4},
+		{"gateway error of a search creation not retried", ContextWithRetryOnRefusal(context.Background()), http.MethodPost, "504", 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			mu.Lock()
+			attempts = 0
+			mu.Unlock()
+
+			resp, err := MakeHttpRequestWithContext(test.ctx, client, test.method, endpoint, nil, url.Values{"status": {test.status}})
+			if err == nil {
+				resp.Body.Close()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if attempts != test.expectedAttempts {
+				t.Fatalf("Expected %d attempts but got %d (%v).", test.expectedAttempts, attempts, err)
+			}
+		})
+	}
+
+	// an error of the authentication is returned by the first attempt
+	mu.Lock()
+	attempts = 0
+	mu.Unlock()
+	wrongClient := newClient("Splunk abc")
+	if _, err := MakeHttpRequest(wrongClient, http.MethodGet, endpoint, nil, nil); err == nil {
+		t.Fatalf("Expected an error for a wrong authentication method.")
+	}
+
+	if isTransportError(fmt.Errorf("wrong authentication method")) || isTransportError(&url.Error{Op: "Get", URL: server.URL, Err: &tls.CertificateVerificationError{}}) {
+		t.Fatalf("Expected the errors of the authentication and of the certificates not to be retried.")
+	}
+	if !isTransportError(&url.Error{Op: "Post", URL: server.URL, Err: syscall.ECONNRESET}) || !isTransportError(fmt.Errorf("read : %w", io.ErrUnexpectedEOF)) {
+		t.Fatalf("Expected the network errors to be retried.")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 0 {
+		t.Fatalf("Expected no request but got %d.", attempts)
+	}
+}
+
+func TestLimiters(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	serverUrl, _ := url.Parse(server.URL)
+
+	client := mustNew(t, serverUrl.Hostname(),
+		WithPort(serverUrl.Port()),
+		WithScheme("http"),
+		WithToken("my-token"),
+		WithRateLimit(20, 1),
+		WithMaxConcurrentSearches(1),
+	)
+	endpoint := CreateEndpoint(client, "services/search/v2/jobs/")
+
+	// 1 request is sent at once, then 1 every 50ms
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		resp, err := MakeHttpRequest(client, http.MethodGet, endpoint, nil, nil)
+		if err != nil {
+			t.Fatalf("Got an error : %s", err)
+		}
+		resp.Body.Close()
+	}
+	if elapsed := time.Since(start); elapsed < 190*time.Millisecond {
+		t.Fatalf("Expected the requests to be spread over 200ms but they took %v.", elapsed)
+	}
+
+	// the only slot is held : the second search waits until the context is done
+	slotCtx, release, err := AcquireSearchSlot(context.Background(), client)
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := AcquireSearchSlot(ctx, client); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected %v but got %v.", context.DeadlineExceeded, err)
+	}
+	// the calls made with the context holding the slot do not wait for another one
+	if _, nestedRelease, err := AcquireSearchSlot(slotCtx, client); err != nil {
+		t.Fatalf("Got an error : %s", err)
+	} else {
+		nestedRelease()
+	}
+	release()
+	release()
+
+	_, release, err = AcquireSearchSlot(context.Background(), client.Namespaced("nobody", "search"))
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	release()
+}
+
+func TestMiddleware(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer my-token" || r.Header.Get("X-Request-Id") != "42" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	serverUrl, _ := url.Parse(server.URL)
+
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next RequestHandler) RequestHandler {
+			return func(req *http.Request) (*http.Response, error) {
+				calls = append(calls, name+" "+OperationFromContext(req.Context()))
+				if req.Header.Get("Authorization") != "" {
+					t.Errorf("Expected the middleware not to see the Authorization header.")
+				}
+				return next(req)
+			}
+		}
+	}
+	addHeader := func(next RequestHandler) RequestHandler {
+		return func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			req.Header.Set("X-Request-Id", "42")
+			return next(req)
+		}
+	}
+	// the first attempt fails without reaching splunk
+	faults := 1
+	injectFault := func(next RequestHandler) RequestHandler {
+		return func(req *http.Request) (*http.Response, error) {
+			if faults > 0 {
+				faults--
+				return &http.Response{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable", Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+			}
+			return next(req)
+		}
+	}
+
+	client := mustNew(t, serverUrl.Hostname(),
+		WithPort(serverUrl.Port()),
+		WithScheme("http"),
+		WithToken("my-token"),
+		WithMiddleware(record("outer"), addHeader, injectFault),
+		WithMiddleware(record("inner")),
+		WithRetry(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}),
+	)
+
+	ctx := ContextWithOperation(context.Background(), "jobs.status")
+	resp, err := MakeHttpRequestWithContext(ctx, client, http.MethodGet, CreateEndpoint(client, "services/search/v2/jobs/"), nil, nil)
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected %v but got %v.", http.StatusOK, resp.Status)
+	}
+
+	expected := []string{"outer jobs.status", "outer jobs.status", "inner jobs.status"}
+	if strings.Join(calls, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected %v but got %v.", expected, calls)
+	}
+
+	if _, err := New("localhost", WithToken("my-token"), WithMiddleware(nil)); err == nil {
+		t.Fatalf("Expected an error for a nil middleware.")
+	}
+}
+
+func TestLogger(t *testing.T) {
+
+	failures := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+	}))
+	defer server.Close()
+	serverUrl, _ := url.Parse(server.URL)
+
+	var logs strings.Builder
+	client := mustNew(t, serverUrl.Hostname(),
+		WithPort(serverUrl.Port()),
+		WithScheme("http"),
+		WithToken("my-secret-token"),
+		WithRetry(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}),
+		WithLogger(slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+	)
+
+	ctx := ContextWithOperation(context.Background(), "jobs.status")
+	params := url.Values{"password": {"my-secret-password"}, "output_mode": {"json"}}
+	resp, err := MakeHttpRequestWithContext(ctx, client, http.MethodGet, CreateEndpoint(client, "services/search/v2/jobs/"), nil, params)
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	resp.Body.Close()
+
+	output := logs.String()
+	if strings.Contains(output, "my-secret") {
+		t.Fatalf("Expected the secrets to be redacted but got %s.", output)
+	}
+	for _, expected := range []string{`"level":"WARN","msg":"splunk request failed"`, `"msg":"retrying the splunk request"`, `"level":"DEBUG","msg":"splunk request"`, `"operation":"jobs.status"`, `"Authorization":["REDACTED"]`, `password=REDACTED`} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %s in the logs but got %s.", expected, output)
+		}
+	}
+}
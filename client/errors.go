@@ -0,0 +1,143 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// types of the messages returned by splunk
+const (
+	MessageTypeInfo  = "INFO"
+	MessageTypeWarn  = "WARN"
+	MessageTypeError = "ERROR"
+	MessageTypeFatal = "FATAL"
+)
+
+// sentinel errors matched by a *SplunkError, depending on its status code, with errors.Is
+var (
+	ErrBadRequest         = errors.New("bad request")
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrForbidden          = errors.New("forbidden")
+	ErrNotFound           = errors.New("not found")
+	ErrConflict           = errors.New("conflict")
+	ErrTooManyRequests    = errors.New("too many requests")
+	ErrServiceUnavailable = errors.New("service unavailable")
+	ErrServerError        = errors.New("server error")
+)
+
+// ErrJobFailed is returned when a search job ends in the FAILED dispatch state or reports a fatal message
+var ErrJobFailed = errors.New("search job failed")
+
+// ErrJobNotDone is returned when the results of a search job are read before it is done, see jobs.WaitForJob
+var ErrJobNotDone = errors.New("search job not done")
+
+// Message is a message returned by splunk along with a response
+type Message struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// SplunkError is the error returned when splunk answers a request with a non 2xx status code
+type SplunkError struct {
+	StatusCode int
+	Status     string
+	Method     string
+	// url of the request, without its query string
+	Endpoint string
+	Messages []Message
+}
+
+func (e *SplunkError) Error() string {
+
+	var texts []string
+	for _, message := range e.Messages {
+		texts = append(texts, message.Type+" "+message.Text)
+	}
+
+	err := fmt.Sprintf("http error : %s on %s %s", e.Status, e.Method, e.Endpoint)
+	if len(texts) > 0 {
+		err += " : " + strings.Join(texts, " ; ")
+	}
+	return err
+}
+
+// Is matches the sentinel error corresponding to the status code
+func (e *SplunkError) Is(target error) bool {
+
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrTooManyRequests:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServiceUnavailable:
+		return e.StatusCode == http.StatusServiceUnavailable
+	case ErrServerError:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// HasMessageType reports whether splunk returned at least one message of the given type
+func (e *SplunkError) HasMessageType(messageType string) bool {
+
+	for _, message := range e.Messages {
+		if message.Type == messageType {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSplunkError creates the error of a failed response from its body
+func NewSplunkError(resp *http.Response, body []byte) *SplunkError {
+
+	splunkErr := &SplunkError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+	}
+	if resp.Request != nil {
+		splunkErr.Method = resp.Request.Method
+		endpoint := *resp.Request.URL
+		endpoint.RawQuery = ""
+		endpoint.User = nil
+		splunkErr.Endpoint = endpoint.String()
+	}
+	splunkErr.Messages, _ = parseMessages(body)
+
+	return splunkErr
+}
+
+// CheckResponse returns a *SplunkError if the status code of the response is not 2xx.
+// The body of the response is only read in that case
+func CheckResponse(resp *http.Response) error {
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return NewSplunkError(resp, body)
+}
+
+// parseMessages returns the messages of a splunk response body
+func parseMessages(body []byte) ([]Message, error) {
+
+	var bodyJson struct {
+		Messages []Message `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &bodyJson); err != nil {
+		return nil, err
+	}
+	return bodyJson.Messages, nil
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WithMaxConcurrentSearches limits the number of searches run at the same time through the client, and its namespaced copies,
+// to stay under the concurrent search quota of the splunk user. The calls wait for a free slot instead of failing
+func WithMaxConcurrentSearches(max int) Option {
+	return func(o *options) error {
+		if max <= 0 {
+			return fmt.Errorf("the maximum number of concurrent searches must be positive")
+		}
+		o.maxSearches = max
+		return nil
+	}
+}
+
+// WithRateLimit limits the requests sent to splunk to requestsPerSecond on average, with bursts of up to burst requests.
+// The requests wait for their turn instead of failing
+func WithRateLimit(requestsPerSecond float64, burst int) Option {
+	return func(o *options) error {
+		if requestsPerSecond <= 0 || burst <= 0 {
+			return fmt.Errorf("the rate limit and its burst must be positive")
+		}
+		o.rateLimiter = newRateLimiter(requestsPerSecond, burst)
+		return nil
+	}
+}
+
+type searchSlotKey struct{}
+
+// AcquireSearchSlot waits for a free search slot of the client, or for ctx to be done.
+// The returned context tells the functions of the SDK that the slot is held, so they do not wait for another one.
+// release must be called once the search is over. Without WithMaxConcurrentSearches, a slot is always free
+//
+//	ctx, release, err := splunk.AcquireSearchSlot(ctx, client)
+//	if err != nil {
+//		...
+//	}
+//	defer release()
+//	sid, err := jobs.CreateJobWithContext(ctx, client, &spRequest, "")
+//	...
+//	_, err = jobs.WaitForJob(ctx, client, sid, nil)
+func AcquireSearchSlot(ctx context.Context, client *SplunkClient) (context.Context, func(), error) {
+
+	if client.searchSlots == nil || ctx.Value(searchSlotKey{}) != nil {
+		return ctx, func() {}, nil
+	}
+
+	select {
+	case client.searchSlots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx, nil, fmt.Errorf("waiting for a search slot : %w", ctx.Err())
+	}
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() { <-client.searchSlots })
+	}
+	return context.WithValue(ctx, searchSlotKey{}, true), release, nil
+}
+
+// rateLimiter is a token bucket refilled at rate tokens per second, holding up to burst tokens
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+
+	return &rateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait takes a token, waiting for it to be available or for ctx to be done
+func (l *rateLimiter) wait(ctx context.Context) error {
+
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	// the token is reserved now, a negative balance being the wait of the queued requests
+	l.tokens--
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// give the token back to the next requests
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return fmt.Errorf("waiting for the rate limit : %w", ctx.Err())
+	}
+}
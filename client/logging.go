@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const redacted = "REDACTED"
+
+// headers and parameters whose values are never logged
+var (
+	secretHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"}
+	secretParams  = []string{"password", "token", "session_key", "sessionKey"}
+)
+
+// WithLogger logs the requests made to splunk, their responses, retries and logins. Secrets such as the Authorization header are redacted.
+// Successful requests are logged at the debug level, failed ones at the warn or error level. Without a logger the SDK logs nothing
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) error {
+		if logger == nil {
+			return fmt.Errorf("the logger must not be nil")
+		}
+		o.logger = logger
+		return nil
+	}
+}
+
+// log returns the logger of the client, which discards everything if the client has none
+func (client *SplunkClient) log() *slog.Logger {
+
+	if client.logger == nil {
+		return slog.New(discardHandler{})
+	}
+	return client.logger
+}
+
+// logMiddleware logs each request sent to splunk with its response
+func logMiddleware(logger *slog.Logger) Middleware {
+	return func(next RequestHandler) RequestHandler {
+		return func(req *http.Request) (*http.Response, error) {
+
+			ctx := req.Context()
+			start := time.Now()
+			resp, err := next(req)
+
+			attrs := []slog.Attr{
+				slog.String("operation", OperationFromContext(ctx)),
+				slog.String("method", req.Method),
+				slog.String("url", redactURL(req.URL)),
+				slog.Duration("duration", time.Since(start)),
+			}
+			switch {
+			case err != nil:
+				attrs = append(attrs, slog.String("error", err.Error()))
+				logger.LogAttrs(ctx, slog.LevelError, "splunk request failed", attrs...)
+			case resp.StatusCode >= http.StatusBadRequest:
+				attrs = append(attrs, slog.Int("status", resp.StatusCode))
+				logger.LogAttrs(ctx, slog.LevelWarn, "splunk request failed", attrs...)
+			default:
+				attrs = append(attrs, slog.Int("status", resp.StatusCode))
+				if logger.Enabled(ctx, slog.LevelDebug) {
+					attrs = append(attrs, slog.Any("headers", redactHeaders(req.Header)))
+				}
+				logger.LogAttrs(ctx, slog.LevelDebug, "splunk request", attrs...)
+			}
+			return resp, err
+		}
+	}
+}
+
+// redactHeaders returns a copy of the headers without the values of the secret ones
+func redactHeaders(header http.Header) http.Header {
+
+	redactedHeader := header.Clone()
+	for _, name := range secretHeaders {
+		if redactedHeader.Get(name) != "" {
+			redactedHeader.Set(name, redacted)
+		}
+	}
+	return redactedHeader
+}
+
+// redactURL returns the url without its user info and the values of the secret parameters
+func redactURL(u *url.URL) string {
+
+	redactedURL := *u
+	redactedURL.User = nil
+	if redactedURL.RawQuery != "" {
+		query := redactedURL.Query()
+		for name := range query {
+			for _, secret := range secretParams {
+				if strings.EqualFold(name, secret) {
+					query.Set(name, redacted)
+				}
+			}
+		}
+		redactedURL.RawQuery = query.Encode()
+	}
+	return redactedURL.String()
+}
+
+// discardHandler drops every record
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
@@ -0,0 +1,140 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// OperationLogin is the operation name of the requests logging in to get a session key
+const OperationLogin = "auth.login"
+
+// RequestHandler sends a request to splunk and returns its response
+type RequestHandler func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps the handler of every request made by the client, to change the request, watch the response or replace it.
+// The logical operation of the request, such as "jobs.create" or "alerts.delete", is given by OperationFromContext(req.Context()).
+// A middleware must not modify the request it is given, but a clone of it
+//
+//	func withHeader(next splunk.RequestHandler) splunk.RequestHandler {
+//		return func(req *http.Request) (*http.Response, error) {
+//			req = req.Clone(req.Context())
+//			req.Header.Set("X-Request-Id", uuid.NewString())
+//			return next(req)
+//		}
+//	}
+type Middleware func(next RequestHandler) RequestHandler
+
+// WithMiddleware adds middlewares to the client, the first one being the outermost.
+// They wrap the authentication of the requests, so the Authorization header is never seen by them,
+// and they are run again for each attempt of the retry policy
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(o *options) error {
+		for _, middleware := range middlewares {
+			if middleware == nil {
+				return fmt.Errorf("the middleware must not be nil")
+			}
+		}
+		o.middlewares = append(o.middlewares, middlewares...)
+		return nil
+	}
+}
+
+type operationKey struct{}
+
+// ContextWithOperation names the logical operation of the requests made with the context
+func ContextWithOperation(ctx context.Context, operation string) context.Context {
+
+	return context.WithValue(ctx, operationKey{}, operation)
+}
+
+// OperationFromContext returns the logical operation of the requests made with the context, or an empty string if it has none
+func OperationFromContext(ctx context.Context) string {
+
+	operation, _ := ctx.Value(operationKey{}).(string)
+	return operation
+}
+
+// handler returns the chain of the middlewares of the client, ending with the authentication of the request if authenticate is true
+func (client *SplunkClient) handler(authenticate bool) RequestHandler {
+
+	handler := func(req *http.Request) (*http.Response, error) {
+		if err := client.rateLimiter.wait(req.Context()); err != nil {
+			return nil, err
+		}
+		return client.Client.Do(req)
+	}
+	// the requests are logged once authenticated, with their Authorization header redacted
+	if client.logger != nil {
+		handler = logMiddleware(client.logger)(handler)
+	}
+	if authenticate {
+		handler = authMiddleware(client)(handler)
+	}
+	for i := len(client.middlewares) - 1; i >= 0; i-- {
+		handler = client.middlewares[i](handler)
+	}
+	// the span of a request covers the middlewares of the caller
+	if client.tel != nil {
+		handler = traceMiddleware(client.tel)(handler)
+	}
+	return handler
+}
+
+// authMiddleware sets the Authorization header of the requests.
+// With a session, the key is renewed once if it has expired and the request is sent again
+func authMiddleware(client *SplunkClient) Middleware {
+	return func(next RequestHandler) RequestHandler {
+		return func(req *http.Request) (*http.Response, error) {
+
+			if client.session == nil {
+				token, err := CreateAuthenticationKey(client)
+				if err != nil {
+					return nil, err
+				}
+				req = req.Clone(req.Context())
+				req.Header.Set("Authorization", token)
+				return next(req)
+			}
+
+			ctx := req.Context()
+			sessionKey, err := client.session.sessionKey(ctx, client)
+			if err != nil {
+				return nil, err
+			}
+			authReq := req.Clone(ctx)
+			authReq.Header.Set("Authorization", "Splunk "+sessionKey)
+
+			resp, err := next(authReq)
+			if err != nil || resp.StatusCode != http.StatusUnauthorized {
+				return resp, err
+			}
+
+			// the session key has expired : log in again and retry once
+			resp.Body.Close()
+			if sessionKey, err = client.session.renew(ctx, client, sessionKey); err != nil {
+				return nil, err
+			}
+			authReq, err = rewindRequest(req)
+			if err != nil {
+				return nil, err
+			}
+			authReq.Header.Set("Authorization", "Splunk "+sessionKey)
+			return next(authReq)
+		}
+	}
+}
+
+// rewindRequest returns a clone of the request with a fresh body, so it can be sent again
+func rewindRequest(req *http.Request) (*http.Request, error) {
+
+	clone := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		clone.Body = body
+	}
+	return clone, nil
+}
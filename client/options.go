@@ -0,0 +1,266 @@
+package client
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const defaultPort = "8089"
+
+// Option configures the client created by New
+type Option func(*options) error
+
+// options gathers the settings given to New before the client is built
+type options struct {
+	port           string
+	httpClient     *http.Client
+	transport      http.RoundTripper
+	token          string
+	sessionKey     string
+	username       string
+	password       string
+	login          bool
+	authMethod     string
+	skipSSL        bool
+	scheme         string
+	basePath       string
+	userAgent      string
+	owner          string
+	app            string
+	retry          *RetryPolicy
+	maxSearches    int
+	rateLimiter    *rateLimiter
+	middlewares    []Middleware
+	logger         *slog.Logger
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	tls            tlsOptions
+}
+
+// New creates a client of the Splunk instance running on host, configured by the options.
+// Exactly one authentication option must be given. By default the client uses https on the port 8089,
+// a host given as a url without port using the default port of its scheme
+//
+//	client, err := splunk.New("localhost",
+//		splunk.WithToken(token),
+//		splunk.WithHTTPClient(&http.Client{Timeout: time.Minute}),
+//	)
+func New(host string, opts ...Option) (*SplunkClient, error) {
+
+	o := options{}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	// the port of a url is the one of its scheme, unless it is given by WithPort
+	if o.port == "" && !strings.Contains(host, "://") {
+		o.port = defaultPort
+	}
+	if o.authMethod == "" {
+		return nil, fmt.Errorf("no authentication method provided")
+	}
+
+	// the http client of the caller is copied, not modified
+	httpClient := &http.Client{}
+	if o.httpClient != nil {
+		*httpClient = *o.httpClient
+	}
+	if o.transport != nil {
+		httpClient.Transport = o.transport
+	}
+	if o.skipSSL || o.tls.isSet() {
+		transport, err := withTLSConfig(httpClient.Transport, func(config *tls.Config) {
+			if o.skipSSL {
+				config.InsecureSkipVerify = true
+			}
+			o.tls.configure(config)
+		})
+		if err != nil {
+			return nil, err
+		}
+		httpClient.Transport = transport
+	}
+
+	client := &SplunkClient{
+		Client:      httpClient,
+		Host:        host,
+		Port:        o.port,
+		Token:       o.token,
+		Username:    o.username,
+		Password:    o.password,
+		SessionKey:  o.sessionKey,
+		SkipSSL:     o.skipSSL,
+		Scheme:      o.scheme,
+		BasePath:    o.basePath,
+		UserAgent:   o.userAgent,
+		Owner:       o.owner,
+		App:         o.app,
+		retry:       o.retry,
+		rateLimiter: o.rateLimiter,
+		middlewares: o.middlewares,
+		logger:      o.logger,
+	}
+	if o.login {
+		client.session = &session{}
+	}
+	if o.tracerProvider != nil || o.meterProvider != nil {
+		tel, err := newTelemetry(o.tracerProvider, o.meterProvider)
+		if err != nil {
+			return nil, err
+		}
+		client.tel = tel
+	}
+	if o.maxSearches > 0 {
+		client.searchSlots = make(chan struct{}, o.maxSearches)
+	}
+
+	return client, nil
+}
+
+// WithPort sets the port of the management API of splunk (8089 by default), used when the host has no port
+func WithPort(port string) Option {
+	return func(o *options) error {
+		o.port = port
+		return nil
+	}
+}
+
+// WithToken authenticates the requests with a Splunk authentication token
+func WithToken(token string) Option {
+	return func(o *options) error {
+		o.token = token
+		return o.setAuthMethod("token")
+	}
+}
+
+// WithSessionKey authenticates the requests with an existing session key
+func WithSessionKey(sessionKey string) Option {
+	return func(o *options) error {
+		o.sessionKey = sessionKey
+		return o.setAuthMethod("session key")
+	}
+}
+
+// WithBasicAuth authenticates every request with the username and password
+func WithBasicAuth(username string, password string) Option {
+	return func(o *options) error {
+		o.username, o.password = username, password
+		return o.setAuthMethod("basic authentication")
+	}
+}
+
+// WithSessionLogin logs in with the username and password and authenticates the requests with the session key obtained.
+// The client logs in again when the session expires
+func WithSessionLogin(username string, password string) Option {
+	return func(o *options) error {
+		o.username, o.password = username, password
+		o.login = true
+		return o.setAuthMethod("session login")
+	}
+}
+
+// WithHTTPClient sets the http client used to make the requests. It is copied, so the client given is never modified
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) error {
+		if client == nil {
+			return fmt.Errorf("the http client must not be nil")
+		}
+		o.httpClient = client
+		return nil
+	}
+}
+
+// WithTransport sets the transport of the http client. TLS options are applied on a copy of it
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) error {
+		if transport == nil {
+			return fmt.Errorf("the transport must not be nil")
+		}
+		o.transport = transport
+		return nil
+	}
+}
+
+// WithInsecureSkipVerify disables the verification of the certificate of splunk
+func WithInsecureSkipVerify() Option {
+	return func(o *options) error {
+		o.skipSSL = true
+		return nil
+	}
+}
+
+// WithScheme sets the scheme of the urls, https by default
+func WithScheme(scheme string) Option {
+	return func(o *options) error {
+		if scheme != "http" && scheme != "https" {
+			return fmt.Errorf("unsupported scheme %q", scheme)
+		}
+		o.scheme = scheme
+		return nil
+	}
+}
+
+// WithBasePath sets the path prefix under which splunk is served, when it is behind a reverse proxy
+func WithBasePath(basePath string) Option {
+	return func(o *options) error {
+		o.basePath = strings.Trim(basePath, "/")
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header of the requests
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) error {
+		o.userAgent = userAgent
+		return nil
+	}
+}
+
+// WithNamespace makes the requests in the namespace of the owner and the app, NamespaceWildcard matching all of them.
+// See SplunkClient.Namespaced to change the namespace of a single call
+func WithNamespace(owner string, app string) Option {
+	return func(o *options) error {
+		if owner == "" || app == "" {
+			return fmt.Errorf("the owner and the app of the namespace must not be empty")
+		}
+		o.owner, o.app = owner, app
+		return nil
+	}
+}
+
+func (o *options) setAuthMethod(method string) error {
+
+	if o.authMethod != "" {
+		return fmt.Errorf("several authentication methods provided : %s and %s", o.authMethod, method)
+	}
+	o.authMethod = method
+	return nil
+}
+
+// withTLSConfig returns a copy of the transport whose TLS configuration is changed by configure.
+// A nil transport stands for http.DefaultTransport
+func withTLSConfig(transport http.RoundTripper, configure func(*tls.Config)) (http.RoundTripper, error) {
+
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	httpTransport, ok := transport.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("TLS options need an *http.Transport, got %T", transport)
+	}
+
+	httpTransport = httpTransport.Clone()
+	if httpTransport.TLSClientConfig == nil {
+		httpTransport.TLSClientConfig = &tls.Config{}
+	}
+	configure(httpTransport.TLSClientConfig)
+
+	return httpTransport, nil
+}
@@ -1,14 +1,87 @@
 package client
 
 import (
+	"context"
 	"encoding/base64"
-	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
 )
 
+// CreateEndpoint returns the url of the given service on the Splunk instance of the client.
+//
+// The host of the client is either a host name or a base url such as http://proxy:8000/splunk,
+// whose scheme, port and path prefix are kept. Scheme and BasePath of the client take precedence,
+// and the port of the client is used when the host has none. Each segment of the service is escaped.
+// When the client has a namespace, the services/ prefix of the service becomes servicesNS/{owner}/{app}/
+func CreateEndpoint(client *SplunkClient, service string) string {
+	base := parseHost(client.Host)
+
+	scheme := client.Scheme
+	if scheme == "" {
+		scheme = base.Scheme
+	}
+	if scheme == "" {
+		scheme = "https"
+	}
+
+	host := base.Host
+	// a bare IPv6 address has colons but no port
+	hasPort := base.Port() != "" && (strings.HasPrefix(host, "[") || strings.Count(host, ":") == 1)
+	if !hasPort && client.Port != "" {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), client.Port)
+	}
+
+	var segments []string
+	for _, prefix := range []string{base.Path, client.BasePath} {
+		if prefix = strings.Trim(prefix, "/"); prefix != "" {
+			segments = append(segments, strings.Split(prefix, "/")...)
+		}
+	}
+	segments = append(segments, strings.Split(namespaceService(client, strings.TrimPrefix(service, "/")), "/")...)
+
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return scheme + "://" + host + "/" + strings.Join(segments, "/")
+}
+
+// namespaceService moves a global service under the namespace of the client, if any.
+// Services already in a namespace are kept as is
+func namespaceService(client *SplunkClient, service string) string {
+
+	if (client.Owner == "" && client.App == "") || !strings.HasPrefix(service, "services/") {
+		return service
+	}
+
+	owner, app := client.Owner, client.App
+	if owner == "" {
+		owner = NamespaceWildcard
+	}
+	if app == "" {
+		app = NamespaceWildcard
+	}
+	return "servicesNS/" + owner + "/" + app + "/" + strings.TrimPrefix(service, "services/")
+}
+
+// parseHost splits the host of a client, which may be a bare host name, a host and a port or a full url
+func parseHost(host string) *url.URL {
+	host = strings.TrimSpace(host)
+
+	if strings.Contains(host, "://") {
+		if base, err := url.Parse(host); err == nil {
+			return base
+		}
+	}
+	// the path prefix of a host given without scheme
+	host, path, _ := strings.Cut(host, "/")
+
+	return &url.URL{Host: host, Path: path}
+}
+
 // create an authentication key depending on the method provided
 //
 //	three methods are available:
@@ -43,49 +116,79 @@ func CreateAuthenticationKey(client *SplunkClient) (string, error) {
 	return "", fmt.Errorf("no authentication method provided")
 }
 
-// return the message of the error when got http error
+// return the messages of the error when got http error
+// see CheckResponse to get a *SplunkError with the type of each message
 func HandleHttpError(body []byte) (string, error) {
 
-	var bodyJson map[string][]map[string]string
-	errUmarshall := json.Unmarshal([]byte(body), &bodyJson)
-	if errUmarshall != nil {
-		return "", errUmarshall
+	messages, err := parseMessages(body)
+	if err != nil {
+		return "", err
+	}
+	if len(messages) == 0 {
+		return "", fmt.Errorf("incorrect format")
 	}
 
-	if len(bodyJson) > 1 {
-		return bodyJson["messages"][0]["text"], nil
+	texts := make([]string, len(messages))
+	for i, message := range messages {
+		texts[i] = message.Text
 	}
-	return "", fmt.Errorf("incorrect format")
+	return strings.Join(texts, " ; "), nil
 }
 
-// MakeHttpRequest creates a new http request - depending on the method (GET, POST, DELETE,...) - and returns the response
-func MakeHttpRequest(client *SplunkClient, method string, spRequestHeaders map[string]string, params url.Values) (*http.Response, error) {
+// MakeHttpRequest creates a new http request to the endpoint - depending on the method (GET, POST, DELETE,...) - and returns the response
+func MakeHttpRequest(client *SplunkClient, method string, endpoint string, spRequestHeaders map[string]string, params url.Values) (*http.Response, error) {
 
-	// create a new request
-	req, err := http.NewRequest(method, client.Endpoint, strings.NewReader(params.Encode()))
-	if err != nil {
-		return nil, err
-	}
-	// add the headers
-	if spRequestHeaders == nil {
-		spRequestHeaders = map[string]string{}
+	return MakeHttpRequestWithContext(context.Background(), client, method, endpoint, spRequestHeaders, params)
+}
+
+// MakeHttpRequestWithContext is like MakeHttpRequest but the request is bound to ctx,
+// so a cancellation or a deadline aborts the call to Splunk
+func MakeHttpRequestWithContext(ctx context.Context, client *SplunkClient, method string, endpoint string, spRequestHeaders map[string]string, params url.Values) (*http.Response, error) {
+
+	// the parameters of a GET or a DELETE request are sent in the query string, the others in the body
+	body := ""
+	switch method {
+	case http.MethodGet, http.MethodDelete:
+		if len(params) > 0 {
+			separator := "?"
+			if strings.Contains(endpoint, "?") {
+				separator = "&"
+			}
+			endpoint += separator + params.Encode()
+		}
+	default:
+		body = params.Encode()
 	}
 
-	token, err := CreateAuthenticationKey(client)
+	handler := client.handler(true)
+	return client.retry.do(ctx, client.log(), method, func() (*http.Response, error) {
+		req, err := newHttpRequest(ctx, client, method, endpoint, spRequestHeaders, body)
+		if err != nil {
+			return nil, err
+		}
+		return handler(req)
+	})
+}
+
+// newHttpRequest creates the request with its headers, the Authorization header being set by the middleware of the client
+func newHttpRequest(ctx context.Context, client *SplunkClient, method string, endpoint string, spRequestHeaders map[string]string, body string) (*http.Request, error) {
+
+	// create a new request
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, strings.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
-	spRequestHeaders["Authorization"] = token
 
+	// add the headers : the map given by the caller is left untouched since it may be shared
 	for header, val := range spRequestHeaders {
 		req.Header.Add(header, val)
 	}
-	// get the response
-	resp, err := client.Client.Do(req)
-
-	if err != nil {
-		return nil, err
+	if client.UserAgent != "" {
+		req.Header.Set("User-Agent", client.UserAgent)
+	}
+	if body != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	}
 
-	return resp, nil
+	return req, nil
 }
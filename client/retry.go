@@ -0,0 +1,219 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// default values of the retry policy
+const (
+	defaultRetryInitialBackoff = 500 * time.Millisecond
+	defaultRetryMaxBackoff     = 30 * time.Second
+	defaultRetryMultiplier     = 2
+	defaultRetryJitter         = 0.2
+)
+
+// RetryPolicy retries the requests that failed because splunk was busy, unreachable or out of search quota.
+//
+// Only the idempotent requests (GET, HEAD, OPTIONS, PUT and DELETE) and the calls marked with ContextWithRetrySafe are retried
+// on a network error or a retryable status. The calls marked with ContextWithRetryOnRefusal, such as the creation of a search job,
+// are only retried when splunk refused them. The errors of the authentication and of the verification of the certificates are never retried
+type RetryPolicy struct {
+	// maximum number of attempts, the first one included. 0 or 1 disables the retries
+	MaxAttempts int
+	// wait before the first retry (500ms by default)
+	InitialBackoff time.Duration
+	// maximum wait between two attempts (30s by default). A longer Retry-After sent by splunk is still honored
+	MaxBackoff time.Duration
+	// factor applied to the wait after each attempt (2 by default)
+	Multiplier float64
+	// fraction of the wait randomly added or removed, so that clients do not retry at the same time (0.2 by default, negative to disable)
+	Jitter float64
+	// status codes retried (429, 502, 503 and 504 by default). Network errors, such as a refused connection or a timeout,
+	// are retried too, except for the calls marked with ContextWithRetryOnRefusal
+	RetryableStatusCodes []int
+}
+
+// WithRetry retries the failed requests according to the policy
+func WithRetry(policy RetryPolicy) Option {
+	return func(o *options) error {
+		o.retry = &policy
+		return nil
+	}
+}
+
+// retryMode tells when a request can be sent again
+type retryMode int
+
+const (
+	retryNever retryMode = iota
+	// only when splunk refused the request, which then had no effect
+	retryOnRefusal
+	retryAlways
+)
+
+type retryModeKey struct{}
+
+// ContextWithRetrySafe marks the calls made with the context as safe to retry, whatever their method.
+// Use it for the requests which have no effect when they are sent twice
+func ContextWithRetrySafe(ctx context.Context) context.Context {
+
+	return context.WithValue(ctx, retryModeKey{}, retryAlways)
+}
+
+// ContextWithRetryOnRefusal marks the calls made with the context as safe to retry only when splunk refused them with a
+// 429 or a 503 status, whatever their method. Use it for the requests which must not be sent twice once splunk handled them,
+// such as the dispatch of a search : after a network error or a timeout, the search may be running already
+func ContextWithRetryOnRefusal(ctx context.Context) context.Context {
+
+	return context.WithValue(ctx, retryModeKey{}, retryOnRefusal)
+}
+
+// retryModeOf returns when a request with the method can be sent again, the mode of the context taking precedence
+func retryModeOf(ctx context.Context, method string) retryMode {
+
+	if mode, ok := ctx.Value(retryModeKey{}).(retryMode); ok {
+		return mode
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return retryAlways
+	}
+	return retryNever
+}
+
+// do calls send until it succeeds, the error is not retryable, the attempts are exhausted or ctx is done.
+// The response or the error of the last attempt is returned
+func (p *RetryPolicy) do(ctx context.Context, logger *slog.Logger, method string, send func() (*http.Response, error)) (*http.Response, error) {
+
+	mode := retryModeOf(ctx, method)
+	if p == nil || p.MaxAttempts <= 1 || mode == retryNever {
+		return send()
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := send()
+		if attempt >= p.MaxAttempts || !p.isRetryable(ctx, mode, resp, err) {
+			return resp, err
+		}
+
+		wait := p.backoff(attempt)
+		attrs := []slog.Attr{slog.String("operation", OperationFromContext(ctx)), slog.Int("attempt", attempt)}
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok && retryAfter > wait {
+				wait = retryAfter
+			}
+			attrs = append(attrs, slog.Int("status", resp.StatusCode))
+			// the connection can be reused once the body is read
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		} else {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		attrs = append(attrs, slog.Duration("wait", wait))
+		logger.LogAttrs(ctx, slog.LevelWarn, "retrying the splunk request", attrs...)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *RetryPolicy) isRetryable(ctx context.Context, mode retryMode, resp *http.Response, err error) bool {
+
+	if err != nil {
+		// the request may have reached splunk unless it is retryable whatever happened. No retry either once the caller gave up
+		return mode == retryAlways && ctx.Err() == nil && isTransportError(err)
+	}
+
+	// a gateway error does not tell whether splunk handled the request
+	if mode == retryOnRefusal && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return false
+	}
+	statusCodes := p.RetryableStatusCodes
+	if len(statusCodes) == 0 {
+		statusCodes = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	}
+	for _, statusCode := range statusCodes {
+		if resp.StatusCode == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// isTransportError reports whether the request failed on its way to splunk or back, such as a refused or reset connection or a timeout.
+// The other errors, such as the ones of the authentication or of the verification of the certificates, would happen again
+func isTransportError(err error) bool {
+
+	var alertErr tls.AlertError
+	var certErr *tls.CertificateVerificationError
+	if errors.As(err, &alertErr) || errors.As(err, &certErr) {
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	// the errors of the http client are *url.Error, a net.Error whatever the error it wraps
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// backoff returns the wait after the attempt
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+
+	initial, max, multiplier, jitter := p.InitialBackoff, p.MaxBackoff, p.Multiplier, p.Jitter
+	if initial <= 0 {
+		initial = defaultRetryInitialBackoff
+	}
+	if max <= 0 {
+		max = defaultRetryMaxBackoff
+	}
+	if multiplier < 1 {
+		multiplier = defaultRetryMultiplier
+	}
+	if jitter == 0 {
+		jitter = defaultRetryJitter
+	}
+
+	wait := math.Min(float64(initial)*math.Pow(multiplier, float64(attempt-1)), float64(max))
+	if jitter > 0 {
+		wait += wait * jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(wait)
+}
+
+// parseRetryAfter reads the Retry-After header, given either in seconds or as an http date
+func parseRetryAfter(retryAfter string) (time.Duration, bool) {
+
+	if retryAfter == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(retryAfter); err == nil {
+		return time.Until(date), true
+	}
+	return 0, false
+}
@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+const loginPath = "services/auth/login"
+
+// session holds the session key obtained by logging in with the username and password of the client.
+// It is shared by the goroutines using the client, the key being renewed when it expires
+type session struct {
+	mu  sync.Mutex
+	key string
+	// login in progress, nil if none
+	pending *loginCall
+}
+
+// loginCall is a login shared by the requests needing a new session key
+type loginCall struct {
+	// closed when the login ends
+	done chan struct{}
+	err  error
+}
+
+// Login logs in with the username and password of the client and stores the session key used by the next requests.
+// It is only needed to check the credentials early, MakeHttpRequest logs in when the client has no session key yet
+func Login(ctx context.Context, client *SplunkClient) error {
+
+	if client.session == nil {
+		return fmt.Errorf("login : the client does not use session authentication")
+	}
+
+	client.session.mu.Lock()
+	currentKey := client.session.key
+	client.session.mu.Unlock()
+
+	_, err := client.session.refresh(ctx, client, currentKey, true)
+	return err
+}
+
+// sessionKey returns the current session key, logging in if there is none
+func (s *session) sessionKey(ctx context.Context, client *SplunkClient) (string, error) {
+
+	return s.refresh(ctx, client, "", false)
+}
+
+// renew returns a new session key, logging in again unless another request already replaced the expired key
+func (s *session) renew(ctx context.Context, client *SplunkClient, expiredKey string) (string, error) {
+
+	client.log().LogAttrs(ctx, slog.LevelInfo, "the splunk session has expired")
+	return s.refresh(ctx, client, expiredKey, false)
+}
+
+// refresh returns a session key other than staleKey. A single login runs at a time, the other requests waiting for it
+// until their context is done. With force, a login is made even if the current key differs from staleKey
+func (s *session) refresh(ctx context.Context, client *SplunkClient, staleKey string, force bool) (string, error) {
+
+	for {
+		s.mu.Lock()
+		if s.key != "" && s.key != staleKey && !force {
+			key := s.key
+			s.mu.Unlock()
+			return key, nil
+		}
+
+		call := s.pending
+		if call == nil {
+			// this request logs in for the others
+			call = &loginCall{done: make(chan struct{})}
+			s.pending = call
+			s.mu.Unlock()
+
+			key, err := s.login(ctx, client)
+
+			s.mu.Lock()
+			if err == nil {
+				s.key = key
+			}
+			call.err = err
+			s.pending = nil
+			s.mu.Unlock()
+			close(call.done)
+			return key, err
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-call.done:
+		}
+		// the login was cut short by the context of the request making it : try again with this one
+		if call.err != nil && !errors.Is(call.err, context.Canceled) && !errors.Is(call.err, context.DeadlineExceeded) {
+			return "", call.err
+		}
+		force = false
+	}
+}
+
+// login logs in and returns the session key, without storing it
+func (s *session) login(ctx context.Context, client *SplunkClient) (string, error) {
+
+	params := url.Values{}
+	params.Add("username", client.Username)
+	params.Add("password", client.Password)
+	params.Add("output_mode", "json")
+
+	// the login service has no namespace
+	endpoint := CreateEndpoint(client.Namespaced("", ""), loginPath)
+
+	// logging in twice is harmless
+	ctx = ContextWithRetrySafe(ContextWithOperation(ctx, OperationLogin))
+	handler := client.handler(false)
+	client.log().LogAttrs(ctx, slog.LevelInfo, "logging in to splunk", slog.String("username", client.Username))
+	resp, err := client.retry.do(ctx, client.log(), http.MethodPost, func() (*http.Response, error) {
+		req, err := newHttpRequest(ctx, client, http.MethodPost, endpoint, nil, params.Encode())
+		if err != nil {
+			return nil, err
+		}
+		return handler(req)
+	})
+	if err != nil {
+		return "", fmt.Errorf("login : error while making the post request : %w", err)
+	}
+	defer resp.Body.Close()
+
+	// handle error
+	if err := CheckResponse(resp); err != nil {
+		return "", fmt.Errorf("login : %w", err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("login : error while getting the body of the post request : %w", err)
+	}
+
+	var login struct {
+		SessionKey string `json:"sessionKey"`
+	}
+	if err := json.Unmarshal(body, &login); err != nil {
+		return "", fmt.Errorf("login : %w", err)
+	}
+	if login.SessionKey == "" {
+		return "", fmt.Errorf("login : no session key found")
+	}
+
+	return login.SessionKey, nil
+}
@@ -0,0 +1,262 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+)
+
+// instrumentationName is the name of the tracer and the meter of the SDK
+const instrumentationName = "github.com/kuro-jojo/splunk-sdk-go"
+
+// attributes of the spans and the metrics
+const (
+	AttributeOperation     = attribute.Key("splunk.operation")
+	AttributeSID           = attribute.Key("splunk.sid")
+	AttributeDispatchState = attribute.Key("splunk.dispatch_state")
+	AttributeResultCount   = attribute.Key("splunk.result_count")
+	AttributeMethod        = attribute.Key("http.request.method")
+	AttributeURL           = attribute.Key("url.full")
+	AttributeEndpoint      = attribute.Key("url.path")
+	AttributeStatusCode    = attribute.Key("http.response.status_code")
+)
+
+// WithTracerProvider emits a span for each request made to splunk and for each search job run by the SDK.
+// No span is emitted by default
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *options) error {
+		if provider == nil {
+			return fmt.Errorf("the tracer provider must not be nil")
+		}
+		o.tracerProvider = provider
+		return nil
+	}
+}
+
+// WithMeterProvider records the duration, the result count and the errors of the searches, and the errors of the requests.
+// No metric is recorded by default
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(o *options) error {
+		if provider == nil {
+			return fmt.Errorf("the meter provider must not be nil")
+		}
+		o.meterProvider = provider
+		return nil
+	}
+}
+
+// telemetry holds the tracer and the instruments of a client
+type telemetry struct {
+	tracer         trace.Tracer
+	searchDuration metric.Float64Histogram
+	searchResults  metric.Int64Histogram
+	errors         metric.Int64Counter
+}
+
+var noopTelemetry = sync.OnceValue(func() *telemetry {
+	telemetry, _ := newTelemetry(tracenoop.NewTracerProvider(), metricnoop.NewMeterProvider())
+	return telemetry
+})
+
+func newTelemetry(tracerProvider trace.TracerProvider, meterProvider metric.MeterProvider) (*telemetry, error) {
+
+	if tracerProvider == nil {
+		tracerProvider = tracenoop.NewTracerProvider()
+	}
+	if meterProvider == nil {
+		meterProvider = metricnoop.NewMeterProvider()
+	}
+	meter := meterProvider.Meter(instrumentationName)
+
+	searchDuration, err := meter.Float64Histogram("splunk.search.duration",
+		metric.WithDescription("Duration of the searches, from the creation of the job to the reading of its results"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	searchResults, err := meter.Int64Histogram("splunk.search.results",
+		metric.WithDescription("Number of results of the searches"),
+		metric.WithUnit("{result}"))
+	if err != nil {
+		return nil, err
+	}
+	errors, err := meter.Int64Counter("splunk.errors",
+		metric.WithDescription("Number of failed requests and searches"),
+		metric.WithUnit("{error}"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &telemetry{
+		tracer:         tracerProvider.Tracer(instrumentationName),
+		searchDuration: searchDuration,
+		searchResults:  searchResults,
+		errors:         errors,
+	}, nil
+}
+
+// telemetry returns the telemetry of the client, which records nothing if the client has none
+func (client *SplunkClient) telemetry() *telemetry {
+
+	if client.tel == nil {
+		return noopTelemetry()
+	}
+	return client.tel
+}
+
+// traceMiddleware emits a span for each request sent to splunk and counts the failed ones
+func traceMiddleware(tel *telemetry) Middleware {
+	return func(next RequestHandler) RequestHandler {
+		return func(req *http.Request) (*http.Response, error) {
+
+			operation := OperationFromContext(req.Context())
+			name := operation
+			if name == "" {
+				name = "splunk " + req.Method
+			}
+			attrs := []attribute.KeyValue{
+				AttributeOperation.String(operation),
+				AttributeMethod.String(req.Method),
+				AttributeURL.String(redactURL(req.URL)),
+				AttributeEndpoint.String(req.URL.Path),
+			}
+			if search := searchFromContext(req.Context()); search != nil {
+				if sid := search.getSID(); sid != "" {
+					attrs = append(attrs, AttributeSID.String(sid))
+				}
+			}
+
+			ctx, span := tel.tracer.Start(req.Context(), name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+			defer span.End()
+
+			resp, err := next(req.WithContext(ctx))
+			switch {
+			case err != nil:
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				tel.errors.Add(ctx, 1, metric.WithAttributes(AttributeOperation.String(operation)))
+			case resp.StatusCode >= http.StatusBadRequest:
+				span.SetAttributes(AttributeStatusCode.Int(resp.StatusCode))
+				span.SetStatus(codes.Error, resp.Status)
+				tel.errors.Add(ctx, 1, metric.WithAttributes(AttributeOperation.String(operation), AttributeStatusCode.Int(resp.StatusCode)))
+			default:
+				span.SetAttributes(AttributeStatusCode.Int(resp.StatusCode))
+			}
+			return resp, err
+		}
+	}
+}
+
+type searchSpanKey struct{}
+
+// SearchSpan follows the lifecycle of a search job : its span and the metrics recorded when it ends.
+// It is used by the jobs package, the requests made with its context being children of its span
+type SearchSpan struct {
+	search *search
+	// false for a span started within another one, which it stands for
+	owner bool
+}
+
+// search is the state of a search span, shared by the nested ones
+type search struct {
+	tel       *telemetry
+	span      trace.Span
+	operation string
+	start     time.Time
+
+	mu          sync.Mutex
+	sid         string
+	resultCount int64
+	hasResults  bool
+}
+
+// StartSearchSpan starts the span of a search job named after the operation.
+// Within the context of another search span, that span is returned and only ended by its own caller
+func StartSearchSpan(ctx context.Context, client *SplunkClient, operation string) (context.Context, *SearchSpan) {
+
+	if s := searchFromContext(ctx); s != nil {
+		return ctx, &SearchSpan{search: s}
+	}
+
+	tel := client.telemetry()
+	ctx, span := tel.tracer.Start(ctx, operation, trace.WithAttributes(AttributeOperation.String(operation)))
+	s := &search{
+		tel:       tel,
+		span:      span,
+		operation: operation,
+		start:     time.Now(),
+	}
+	return context.WithValue(ctx, searchSpanKey{}, s), &SearchSpan{search: s, owner: true}
+}
+
+// SetSID records the SID of the job, also given to the spans of the next requests
+func (s *SearchSpan) SetSID(sid string) {
+
+	s.search.mu.Lock()
+	s.search.sid = sid
+	s.search.mu.Unlock()
+	s.search.span.SetAttributes(AttributeSID.String(sid))
+}
+
+// SetDispatchState records the last known dispatch state of the job
+func (s *SearchSpan) SetDispatchState(dispatchState string) {
+
+	s.search.span.SetAttributes(AttributeDispatchState.String(dispatchState))
+}
+
+// SetResultCount records the number of results of the job
+func (s *SearchSpan) SetResultCount(count int64) {
+
+	s.search.mu.Lock()
+	s.search.resultCount, s.search.hasResults = count, true
+	s.search.mu.Unlock()
+	s.search.span.SetAttributes(AttributeResultCount.Int64(count))
+}
+
+// End ends the span and records the metrics of the search, err being the error which ended it if any.
+// It does nothing for a span started within another one
+func (s *SearchSpan) End(err error) {
+
+	if !s.owner {
+		return
+	}
+
+	search := s.search
+	search.mu.Lock()
+	defer search.mu.Unlock()
+
+	ctx := trace.ContextWithSpan(context.Background(), search.span)
+	attrs := metric.WithAttributes(AttributeOperation.String(search.operation))
+	if err != nil {
+		search.span.RecordError(err)
+		search.span.SetStatus(codes.Error, err.Error())
+		search.tel.errors.Add(ctx, 1, attrs)
+	}
+	search.tel.searchDuration.Record(ctx, time.Since(search.start).Seconds(), attrs)
+	if search.hasResults {
+		search.tel.searchResults.Record(ctx, search.resultCount, attrs)
+	}
+	search.span.End()
+}
+
+func (s *search) getSID() string {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sid
+}
+
+func searchFromContext(ctx context.Context) *search {
+
+	s, _ := ctx.Value(searchSpanKey{}).(*search)
+	return s
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// tlsOptions are the TLS settings layered on the transport of the client
+type tlsOptions struct {
+	rootCAs      *x509.CertPool
+	serverName   string
+	certificates []tls.Certificate
+	// sha256 fingerprints of the accepted certificates
+	pins [][]byte
+}
+
+// WithCACertificates trusts the certificates of the PEM bundle to verify the certificate of splunk.
+// It can be given several times, the system certificates are no longer trusted
+func WithCACertificates(pemCerts []byte) Option {
+	return func(o *options) error {
+		if o.tls.rootCAs == nil {
+			o.tls.rootCAs = x509.NewCertPool()
+		}
+		if !o.tls.rootCAs.AppendCertsFromPEM(pemCerts) {
+			return fmt.Errorf("no certificate found in the CA bundle")
+		}
+		return nil
+	}
+}
+
+// WithCAFile is like WithCACertificates with a bundle read from a PEM file
+func WithCAFile(path string) Option {
+	return func(o *options) error {
+		pemCerts, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("CA bundle : %w", err)
+		}
+		return WithCACertificates(pemCerts)(o)
+	}
+}
+
+// WithServerName sets the name expected in the certificate of splunk, when it differs from the host
+func WithServerName(serverName string) Option {
+	return func(o *options) error {
+		o.tls.serverName = serverName
+		return nil
+	}
+}
+
+// WithClientCertificate presents the certificate to splunk for mutual TLS authentication
+func WithClientCertificate(certPEM []byte, keyPEM []byte) Option {
+	return func(o *options) error {
+		certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return fmt.Errorf("client certificate : %w", err)
+		}
+		o.tls.certificates = append(o.tls.certificates, certificate)
+		return nil
+	}
+}
+
+// WithClientCertificateFiles is like WithClientCertificate with a certificate and a key read from PEM files
+func WithClientCertificateFiles(certFile string, keyFile string) Option {
+	return func(o *options) error {
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("client certificate : %w", err)
+		}
+		o.tls.certificates = append(o.tls.certificates, certificate)
+		return nil
+	}
+}
+
+// WithPinnedCertificate only accepts a connection when a certificate presented by splunk has the SHA-256 fingerprint.
+// The fingerprint is hex encoded, with or without colons. It can be given several times to allow a rotation
+func WithPinnedCertificate(fingerprint string) Option {
+	return func(o *options) error {
+		pin, err := hex.DecodeString(strings.ReplaceAll(fingerprint, ":", ""))
+		if err != nil || len(pin) != sha256.Size {
+			return fmt.Errorf("invalid SHA-256 fingerprint %q", fingerprint)
+		}
+		o.tls.pins = append(o.tls.pins, pin)
+		return nil
+	}
+}
+
+// isSet reports whether a TLS setting was given
+func (t *tlsOptions) isSet() bool {
+
+	return t.rootCAs != nil || t.serverName != "" || len(t.certificates) > 0 || len(t.pins) > 0
+}
+
+func (t *tlsOptions) configure(config *tls.Config) {
+
+	if t.rootCAs != nil {
+		config.RootCAs = t.rootCAs
+	}
+	if t.serverName != "" {
+		config.ServerName = t.serverName
+	}
+	if len(t.certificates) > 0 {
+		config.Certificates = append(config.Certificates, t.certificates...)
+	}
+	if len(t.pins) > 0 {
+		pins := t.pins
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			for _, certificate := range state.PeerCertificates {
+				fingerprint := sha256.Sum256(certificate.Raw)
+				for _, pin := range pins {
+					if bytes.Equal(fingerprint[:], pin) {
+						return nil
+					}
+				}
+			}
+			return fmt.Errorf("the certificate of splunk does not match any pinned fingerprint")
+		}
+	}
+}
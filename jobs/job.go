@@ -1,11 +1,11 @@
 package jobs
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"strconv"
-	"strings"
 
 	splunk "github.com/kuro-jojo/splunk-sdk-go/client"
 	utils "github.com/kuro-jojo/splunk-sdk-go/pkg/utils"
@@ -14,6 +14,22 @@ import (
 const resutltUri = "results"
 const jobsPathv2 = "services/search/v2/jobs/"
 
+const ExecModeBlocking = "blocking"
+const ExecModeNormal = "normal"
+
+// operation names of the requests, given to the middlewares of the client
+const (
+	OperationCreateJob     = "jobs.create"
+	OperationGetJobStatus  = "jobs.status"
+	OperationGetJobResults = "jobs.results"
+	OperationControlJob    = "jobs.control"
+	OperationDeleteJob     = "jobs.delete"
+	OperationExport        = "jobs.export"
+	// lifecycle of a search job, from its creation to the reading of its results
+	OperationSearch     = "jobs.search"
+	OperationWaitForJob = "jobs.wait"
+)
+
 type SearchRequest struct {
 	Headers map[string]string
 	Params  SearchParams
@@ -23,7 +39,8 @@ type SearchParams struct {
 	// splunk search in spl syntax
 	SearchQuery string
 	OutputMode  string `default:"json"`
-	// splunk returns a job SID only if the job is complete
+	// blocking : splunk returns a job SID only if the job is complete
+	// normal : splunk returns the job SID right away, use WaitForJob to know when it is complete
 	ExecMode string `default:"blocking"`
 	// earliest (inclusive) time bounds for the search
 	EarliestTime string
@@ -32,18 +49,48 @@ type SearchParams struct {
 }
 
 // Return a metric from a new created job
+// the result must be a single row with a single field, see GetNamedMetricFromNewJob and GetMetricsFromNewJob otherwise
 func GetMetricFromNewJob(client *splunk.SplunkClient, spRequest *SearchRequest) (float64, error) {
 
-	sid, err := CreateJob(client, spRequest, jobsPathv2)
+	return GetMetricFromNewJobWithContext(context.Background(), client, spRequest)
+}
+
+// GetMetricFromNewJobWithContext is like GetMetricFromNewJob but stops the search calls when ctx is done
+func GetMetricFromNewJobWithContext(ctx context.Context, client *splunk.SplunkClient, spRequest *SearchRequest) (float64, error) {
+
+	ctx, span := splunk.StartSearchSpan(ctx, client, OperationSearch)
+	metric, err := getMetricFromNewJob(ctx, client, spRequest, span)
+	span.End(err)
+	return metric, err
+}
+
+func getMetricFromNewJob(ctx context.Context, client *splunk.SplunkClient, spRequest *SearchRequest, span *splunk.SearchSpan) (float64, error) {
+
+	// the search slot is held until the results are read
+	ctx, release, err := splunk.AcquireSearchSlot(ctx, client)
+	if err != nil {
+		return -1, err
+	}
+	defer release()
+
+	sid, err := CreateJobWithContext(ctx, client, spRequest, jobsPathv2)
 	if err != nil {
 		return -1, fmt.Errorf("error while creating the job : %w", err)
 	}
 
-	res, err := RetrieveJobResult(client, sid)
+	// an asynchronous job has to be complete before reading its results
+	if spRequest.Params.ExecMode == ExecModeNormal {
+		if _, err = WaitForJob(ctx, client, sid, nil); err != nil {
+			return -1, fmt.Errorf("error while waiting for the job : %w", err)
+		}
+	}
+
+	res, err := RetrieveJobResultWithContext(ctx, client, sid)
 
 	if err != nil {
 		return -1, fmt.Errorf("error while handling the results. Error message : %w", err)
 	}
+	span.SetResultCount(int64(len(res)))
 	// if the result is not a metric
 	if len(res) != 1 {
 		if len(res) == 0 {
@@ -67,27 +114,58 @@ func GetMetricFromNewJob(client *splunk.SplunkClient, spRequest *SearchRequest)
 // this function create a new job and return its SID
 func CreateJob(client *splunk.SplunkClient, spRequest *SearchRequest, service string) (string, error) {
 
+	return CreateJobWithContext(context.Background(), client, spRequest, service)
+}
+
+// CreateJobWithContext is like CreateJob but the post request is bound to ctx
+func CreateJobWithContext(ctx context.Context, client *splunk.SplunkClient, spRequest *SearchRequest, service string) (string, error) {
+
+	ctx, span := splunk.StartSearchSpan(ctx, client, OperationSearch)
+	sid, err := createJob(ctx, client, spRequest, service)
+	if err == nil {
+		span.SetSID(sid)
+		if spRequest.Params.ExecMode != ExecModeNormal {
+			span.SetDispatchState(DispatchStateDone)
+		}
+	}
+	span.End(err)
+	return sid, err
+}
+
+func createJob(ctx context.Context, client *splunk.SplunkClient, spRequest *SearchRequest, service string) (string, error) {
+
+	if service == "" {
+		service = jobsPathv2
+	}
+
+	// a blocking job runs during the request. An asynchronous job keeps running after it, see splunk.AcquireSearchSlot to hold a slot until it is done
+	if spRequest.Params.ExecMode != ExecModeNormal {
+		slotCtx, release, err := splunk.AcquireSearchSlot(ctx, client)
+		if err != nil {
+			return "", err
+		}
+		defer release()
+		ctx = slotCtx
+	}
+
 	// create the endpoint for the request
-	utils.CreateEndpoint(client, jobsPathv2)
+	endpoint := utils.CreateEndpoint(client, service)
 
-	resp, err := PostJob(client, spRequest)
+	// after a network error or a timeout the search may be running already, only a refusal of splunk is retried
+	resp, err := PostJobWithContext(splunk.ContextWithRetryOnRefusal(splunk.ContextWithOperation(ctx, OperationCreateJob)), client, endpoint, spRequest)
 
 	if err != nil {
 		return "", fmt.Errorf("error while making the post request : %w", err)
 	}
+	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
 	// handle error
-	if !strings.HasPrefix(strconv.Itoa(resp.StatusCode), "2") {
-		status, err := splunk.HandleHttpError(body)
-		switch err {
-		case nil:
-			return "", fmt.Errorf("http error :  %s", status)
-		default:
-			return "", fmt.Errorf("http error :  %s", resp.Status)
-		}
+	if err := splunk.CheckResponse(resp); err != nil {
+		return "", err
 	}
 
+	body, err := io.ReadAll(resp.Body)
+
 	if err != nil {
 		return "", fmt.Errorf("error while getting the body of the post request : %w", err)
 	}
@@ -103,35 +181,32 @@ func CreateJob(client *splunk.SplunkClient, spRequest *SearchRequest, service st
 }
 
 // return the result of a job get by its SID
+// multi-value fields are not supported, use RetrieveResultSet for them
 func RetrieveJobResult(client *splunk.SplunkClient, sid string) ([]map[string]string, error) {
 
-	newEndpoint := client.Endpoint + sid
-	// check if the endpoint is correctly formed
-	if !strings.HasSuffix(newEndpoint, "/") {
-		newEndpoint += "/"
-	}
+	return RetrieveJobResultWithContext(context.Background(), client, sid)
+}
+
+// RetrieveJobResultWithContext is like RetrieveJobResult but the get request is bound to ctx
+func RetrieveJobResultWithContext(ctx context.Context, client *splunk.SplunkClient, sid string) ([]map[string]string, error) {
 
-	// the endpoint where to find the corresponding job
-	client.Endpoint = newEndpoint + resutltUri
+	// the endpoint where to find the results of the corresponding job
+	endpoint := utils.CreateEndpoint(client, jobsPathv2+sid+"/"+resutltUri)
 
 	// make the get request
-	getResp, err := GetJob(client)
+	getResp, err := GetJobWithContext(splunk.ContextWithOperation(ctx, OperationGetJobResults), client, endpoint)
 	if err != nil {
 		return nil, fmt.Errorf("error while making the get request : %w", err)
 	}
+	defer getResp.Body.Close()
 
 	// get the body of the response
-	getBody, err := io.ReadAll(getResp.Body)
 	// handle error
-	if !strings.HasPrefix(strconv.Itoa(getResp.StatusCode), "2") {
-		status, err := splunk.HandleHttpError(getBody)
-		switch err {
-		case nil:
-			return nil, fmt.Errorf("http error :  %s", status)
-		default:
-			return nil, fmt.Errorf("http error :  %s", getResp.Status)
-		}
+	if err := splunk.CheckResponse(getResp); err != nil {
+		return nil, err
 	}
+
+	getBody, err := io.ReadAll(getResp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("error while getting the body of the get request : %w", err)
 	}
@@ -0,0 +1,149 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	splunk "github.com/kuro-jojo/splunk-sdk-go/client"
+	utils "github.com/kuro-jojo/splunk-sdk-go/pkg/utils"
+)
+
+const controlUri = "control"
+
+// actions accepted by the control endpoint of a search job
+const (
+	JobActionCancel      = "cancel"
+	JobActionPause       = "pause"
+	JobActionUnpause     = "unpause"
+	JobActionFinalize    = "finalize"
+	JobActionTouch       = "touch"
+	JobActionSetTTL      = "setttl"
+	JobActionSetPriority = "setpriority"
+)
+
+// ControlJob runs an action on the job identified by its SID. params holds the arguments of the action if any
+func ControlJob(ctx context.Context, client *splunk.SplunkClient, sid string, action string, params url.Values) error {
+
+	// an empty sid would target the collection of the jobs
+	if err := checkSID(sid); err != nil {
+		return fmt.Errorf("%s action : %w", action, err)
+	}
+
+	// the parameters of the caller are copied since they may be shared
+	controlParams := url.Values{}
+	for key, values := range params {
+		controlParams[key] = append([]string(nil), values...)
+	}
+	controlParams.Set("action", action)
+	controlParams.Set("output_mode", "json")
+
+	endpoint := utils.CreateEndpoint(client, jobsPathv2+sid+"/"+controlUri)
+	ctx = splunk.ContextWithOperation(ctx, OperationControlJob)
+	if action == JobActionCancel {
+		// a cancelled job is deleted : sent again after a lost response, the action would fail with an unknown sid
+		ctx = splunk.ContextWithRetryOnRefusal(ctx)
+	} else {
+		// the other actions have the same effect when run twice
+		ctx = splunk.ContextWithRetrySafe(ctx)
+	}
+	if err := sendJobRequest(ctx, client, http.MethodPost, endpoint, controlParams); err != nil {
+		return fmt.Errorf("job %s : %s action : %w", sid, action, err)
+	}
+	return nil
+}
+
+// CancelJob stops the job and deletes it
+func CancelJob(ctx context.Context, client *splunk.SplunkClient, sid string) error {
+
+	return ControlJob(ctx, client, sid, JobActionCancel, nil)
+}
+
+// PauseJob suspends the execution of the job
+func PauseJob(ctx context.Context, client *splunk.SplunkClient, sid string) error {
+
+	return ControlJob(ctx, client, sid, JobActionPause, nil)
+}
+
+// UnpauseJob resumes the execution of a paused job
+func UnpauseJob(ctx context.Context, client *splunk.SplunkClient, sid string) error {
+
+	return ControlJob(ctx, client, sid, JobActionUnpause, nil)
+}
+
+// FinalizeJob stops the job, the results found so far are kept
+func FinalizeJob(ctx context.Context, client *splunk.SplunkClient, sid string) error {
+
+	return ControlJob(ctx, client, sid, JobActionFinalize, nil)
+}
+
+// TouchJob extends the expiration time of the job to now + its ttl
+func TouchJob(ctx context.Context, client *splunk.SplunkClient, sid string) error {
+
+	return ControlJob(ctx, client, sid, JobActionTouch, nil)
+}
+
+// SetJobTTL changes the time to live of the job, splunk only handles whole seconds
+func SetJobTTL(ctx context.Context, client *splunk.SplunkClient, sid string, ttl time.Duration) error {
+
+	if ttl < time.Second {
+		return fmt.Errorf("job %s : ttl must be at least one second, got %s", sid, ttl)
+	}
+	params := url.Values{}
+	params.Add("ttl", strconv.Itoa(int(ttl.Seconds())))
+
+	return ControlJob(ctx, client, sid, JobActionSetTTL, params)
+}
+
+// SetJobPriority changes the priority of the job, from 0 (lowest) to 10 (highest)
+func SetJobPriority(ctx context.Context, client *splunk.SplunkClient, sid string, priority int) error {
+
+	if priority < 0 || priority > 10 {
+		return fmt.Errorf("job %s : priority must be between 0 and 10, got %d", sid, priority)
+	}
+	params := url.Values{}
+	params.Add("priority", strconv.Itoa(priority))
+
+	return ControlJob(ctx, client, sid, JobActionSetPriority, params)
+}
+
+// DeleteJob deletes the job and its results
+func DeleteJob(ctx context.Context, client *splunk.SplunkClient, sid string) error {
+
+	// an empty sid would target the collection of the jobs
+	if err := checkSID(sid); err != nil {
+		return fmt.Errorf("deletion : %w", err)
+	}
+
+	params := url.Values{}
+	params.Add("output_mode", "json")
+
+	endpoint := utils.CreateEndpoint(client, jobsPathv2+sid)
+	// sent again after a lost response, the deletion would fail with an unknown sid
+	ctx = splunk.ContextWithRetryOnRefusal(splunk.ContextWithOperation(ctx, OperationDeleteJob))
+	if err := sendJobRequest(ctx, client, http.MethodDelete, endpoint, params); err != nil {
+		return fmt.Errorf("job %s : deletion : %w", sid, err)
+	}
+	return nil
+}
+
+// sendJobRequest makes a request whose response body is only read in case of error
+func sendJobRequest(ctx context.Context, client *splunk.SplunkClient, method string, endpoint string, params url.Values) error {
+
+	resp, err := splunk.MakeHttpRequestWithContext(ctx, client, method, endpoint, nil, params)
+	if err != nil {
+		return fmt.Errorf("error while making the %s request : %w", strings.ToLower(method), err)
+	}
+	defer resp.Body.Close()
+
+	// handle error
+	if err := splunk.CheckResponse(resp); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package jobs
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const decodeTag = "splunk"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// DecodeError tells which field of which row could not be decoded
+type DecodeError struct {
+	// index of the row in the decoded results
+	Row int
+	// name of the splunk field
+	Field string
+	// name of the struct field
+	StructField string
+	Err         error
+}
+
+func (e *DecodeError) Error() string {
+
+	return fmt.Sprintf("decoding of the row %d : field %s into %s : %s", e.Row, e.Field, e.StructField, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+
+	return e.Err
+}
+
+// DecodeResults maps each row onto a new T, which must be a struct.
+// The splunk field of a struct field is given by its `splunk:"fieldname"` tag, or by its name when it has no tag.
+// `splunk:"-"` ignores the struct field and `splunk:"fieldname,required"` fails when the row has no value for it.
+//
+// Strings, booleans, numbers, time.Time, pointers to them and slices of them are supported.
+// A slice gets all the values of a multi-value field, any other type gets the first one
+//
+//	type HostStats struct {
+//		Host     string    `splunk:"host"`
+//		Count    int       `splunk:"count,required"`
+//		Statuses []int     `splunk:"values(status)"`
+//		Last     time.Time `splunk:"_time"`
+//	}
+//
+//	stats, err := jobs.DecodeResults[HostStats](results.Rows)
+func DecodeResults[T any](rows []Row) ([]T, error) {
+
+	decoded := make([]T, len(rows))
+	for i, row := range rows {
+		if err := decodeRow(row, reflect.ValueOf(&decoded[i]).Elem()); err != nil {
+			if decodeErr, ok := err.(*DecodeError); ok {
+				decodeErr.Row = i
+			}
+			return nil, err
+		}
+	}
+	return decoded, nil
+}
+
+// DecodeRow maps a single row onto a new T, see DecodeResults
+func DecodeRow[T any](row Row) (T, error) {
+
+	var decoded T
+	err := decodeRow(row, reflect.ValueOf(&decoded).Elem())
+	return decoded, err
+}
+
+func decodeRow(row Row, target reflect.Value) error {
+
+	if target.Kind() != reflect.Struct {
+		return fmt.Errorf("decoding of the results : %s is not a struct", target.Type())
+	}
+
+	targetType := target.Type()
+	for i := 0; i < targetType.NumField(); i++ {
+		structField := targetType.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+
+		field, required := structField.Name, false
+		if tag, ok := structField.Tag.Lookup(decodeTag); ok {
+			name, options, _ := strings.Cut(tag, ",")
+			if name == "-" {
+				continue
+			}
+			if name != "" {
+				field = name
+			}
+			required = options == "required"
+		}
+
+		values := row[field]
+		if len(values) == 0 {
+			if required {
+				return &DecodeError{Field: field, StructField: structField.Name, Err: ErrFieldNotFound}
+			}
+			continue
+		}
+
+		if err := decodeValues(values, target.Field(i)); err != nil {
+			return &DecodeError{Field: field, StructField: structField.Name, Err: err}
+		}
+	}
+	return nil
+}
+
+// decodeValues sets all the values into a slice, or the first one into any other type
+func decodeValues(values []string, target reflect.Value) error {
+
+	switch {
+	case target.Kind() == reflect.Slice:
+		slice := reflect.MakeSlice(target.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := decodeValue(value, slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		target.Set(slice)
+		return nil
+	case target.Kind() == reflect.Pointer:
+		pointer := reflect.New(target.Type().Elem())
+		if err := decodeValues(values, pointer.Elem()); err != nil {
+			return err
+		}
+		target.Set(pointer)
+		return nil
+	default:
+		return decodeValue(values[0], target)
+	}
+}
+
+func decodeValue(value string, target reflect.Value) error {
+
+	if target.Type() == timeType {
+		t, err := parseTime(value)
+		if err != nil {
+			return err
+		}
+		target.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch target.Kind() {
+	case reflect.String:
+		target.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		target.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, target.Type().Bits())
+		if err != nil {
+			return err
+		}
+		target.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, target.Type().Bits())
+		if err != nil {
+			return err
+		}
+		target.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, target.Type().Bits())
+		if err != nil {
+			return err
+		}
+		target.SetFloat(f)
+	case reflect.Pointer:
+		pointer := reflect.New(target.Type().Elem())
+		if err := decodeValue(value, pointer.Elem()); err != nil {
+			return err
+		}
+		target.Set(pointer)
+	default:
+		return fmt.Errorf("unsupported type %s", target.Type())
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	splunk "github.com/kuro-jojo/splunk-sdk-go/client"
+	utils "github.com/kuro-jojo/splunk-sdk-go/pkg/utils"
+)
+
+const exportUri = "export"
+
+// ExportResult is one row streamed by an export search
+type ExportResult struct {
+	// true while the search is still running and the row may be replaced by a later one
+	Preview bool `json:"preview"`
+	// position of the row in the results
+	Offset int `json:"offset"`
+	// true for the last row of the results
+	LastRow bool `json:"lastrow"`
+	Result  Row  `json:"result"`
+}
+
+// ExportReader iterates over the rows of an export search as splunk produces them
+//
+//	reader, err := jobs.Export(ctx, client, &spRequest)
+//	...
+//	defer reader.Close()
+//	for reader.Next() {
+//		row := reader.Result()
+//		...
+//	}
+//	if err := reader.Err(); err != nil {
+//		...
+//	}
+type ExportReader struct {
+	body    io.ReadCloser
+	decoder *json.Decoder
+	current ExportResult
+	err     error
+	// frees the search slot of the export
+	release func()
+	// span of the export, ended with the reader
+	span    *splunk.SearchSpan
+	results int64
+	closed  bool
+}
+
+// Export runs the search without creating a job and streams its results.
+// The time bounds of the search are taken from the params of the request. The reader must be closed
+func Export(ctx context.Context, client *splunk.SplunkClient, spRequest *SearchRequest) (*ExportReader, error) {
+
+	params := url.Values{}
+	params.Add("output_mode", "json")
+	params.Add("search", utils.ValidateSearchQuery(spRequest.Params.SearchQuery))
+	if spRequest.Params.EarliestTime != "" {
+		params.Add("earliest_time", spRequest.Params.EarliestTime)
+	}
+	if spRequest.Params.LatestTime != "" {
+		params.Add("latest_time", spRequest.Params.LatestTime)
+	}
+
+	endpoint := utils.CreateEndpoint(client, jobsPathv2+exportUri)
+
+	// the search runs until the reader is closed
+	ctx, span := splunk.StartSearchSpan(ctx, client, OperationExport)
+	ctx, release, err := splunk.AcquireSearchSlot(ctx, client)
+	if err != nil {
+		span.End(err)
+		return nil, err
+	}
+
+	// after a network error or a timeout the search may be running already, only a refusal of splunk is retried
+	resp, err := splunk.MakeHttpRequestWithContext(splunk.ContextWithRetryOnRefusal(splunk.ContextWithOperation(ctx, OperationExport)), client, http.MethodPost, endpoint, spRequest.Headers, params)
+	if err != nil {
+		release()
+		err = fmt.Errorf("error while making the post request : %w", err)
+		span.End(err)
+		return nil, err
+	}
+
+	// handle error
+	if err := splunk.CheckResponse(resp); err != nil {
+		resp.Body.Close()
+		release()
+		span.End(err)
+		return nil, err
+	}
+
+	return &ExportReader{
+		body:    resp.Body,
+		decoder: json.NewDecoder(resp.Body),
+		release: release,
+		span:    span,
+	}, nil
+}
+
+// Next reads the next row, it returns false at the end of the stream or on error
+func (r *ExportReader) Next() bool {
+
+	if r.err != nil {
+		return false
+	}
+
+	for {
+		// each line of the stream is a json object
+		var line struct {
+			ExportResult
+			Messages JobMessages `json:"messages"`
+		}
+		if err := r.decoder.Decode(&line); err != nil {
+			if !errors.Is(err, io.EOF) {
+				r.err = fmt.Errorf("error while decoding the export results : %w", err)
+			}
+			return false
+		}
+
+		// lines without result only carry messages of the search
+		if line.Result == nil {
+			for _, message := range line.Messages {
+				if message.Type == "ERROR" || message.Type == "FATAL" {
+					r.err = fmt.Errorf("export search : %w%s", splunk.ErrJobFailed, line.Messages)
+					return false
+				}
+			}
+			continue
+		}
+
+		r.current = line.ExportResult
+		if !r.current.Preview {
+			r.results++
+		}
+		return true
+	}
+}
+
+// Result returns the row read by the last call to Next
+func (r *ExportReader) Result() ExportResult {
+
+	return r.current
+}
+
+// Err returns the error that stopped the iteration, if any
+func (r *ExportReader) Err() error {
+
+	return r.err
+}
+
+// Close releases the connection of the stream and its search slot
+func (r *ExportReader) Close() error {
+
+	if !r.closed {
+		r.closed = true
+		defer r.release()
+		r.span.SetResultCount(r.results)
+		defer r.span.End(r.err)
+	}
+	return r.body.Close()
+}
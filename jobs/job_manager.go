@@ -1,6 +1,7 @@
 package jobs
 
 import (
+	"context"
 	"net/http"
 	"net/url"
 
@@ -8,31 +9,48 @@ import (
 	utils "github.com/kuro-jojo/splunk-sdk-go/pkg/utils"
 )
 
-func PostJob(client *splunk.SplunkClient, spRequest *SearchRequest) (*http.Response, error) {
+func PostJob(client *splunk.SplunkClient, endpoint string, spRequest *SearchRequest) (*http.Response, error) {
 
-	return HttpJobRequest(client, http.MethodPost, spRequest)
+	return PostJobWithContext(context.Background(), client, endpoint, spRequest)
 }
 
-func GetJob(client *splunk.SplunkClient) (*http.Response, error) {
+func PostJobWithContext(ctx context.Context, client *splunk.SplunkClient, endpoint string, spRequest *SearchRequest) (*http.Response, error) {
 
-	return HttpJobRequest(client, http.MethodGet, nil)
+	return HttpJobRequestWithContext(ctx, client, http.MethodPost, endpoint, spRequest)
 }
 
-func HttpJobRequest(client *splunk.SplunkClient, method string, spRequest *SearchRequest) (*http.Response, error) {
+func GetJob(client *splunk.SplunkClient, endpoint string) (*http.Response, error) {
+
+	return GetJobWithContext(context.Background(), client, endpoint)
+}
+
+func GetJobWithContext(ctx context.Context, client *splunk.SplunkClient, endpoint string) (*http.Response, error) {
+
+	return HttpJobRequestWithContext(ctx, client, http.MethodGet, endpoint, nil)
+}
+
+func HttpJobRequest(client *splunk.SplunkClient, method string, endpoint string, spRequest *SearchRequest) (*http.Response, error) {
+
+	return HttpJobRequestWithContext(context.Background(), client, method, endpoint, spRequest)
+}
+
+// HttpJobRequestWithContext is like HttpJobRequest but the request is bound to ctx
+func HttpJobRequestWithContext(ctx context.Context, client *splunk.SplunkClient, method string, endpoint string, spRequest *SearchRequest) (*http.Response, error) {
 
 	if spRequest == nil {
 		spRequest = &SearchRequest{}
 	}
 
-	spRequest.Params.OutputMode = "json"
-	spRequest.Params.ExecMode = "blocking"
-
-	// parameters of the request
+	// parameters of the request : the request itself is not modified since it may be shared
 	params := url.Values{}
-	params.Add("output_mode", spRequest.Params.OutputMode)
-	params.Add("exec_mode", spRequest.Params.ExecMode)
+	params.Add("output_mode", "json")
 
 	if method == http.MethodPost {
+		execMode := spRequest.Params.ExecMode
+		if execMode == "" {
+			execMode = ExecModeBlocking
+		}
+		params.Add("exec_mode", execMode)
 		params.Add("search", utils.ValidateSearchQuery(spRequest.Params.SearchQuery))
 		if spRequest.Params.EarliestTime != "" {
 			params.Add("earliest_time", spRequest.Params.EarliestTime)
@@ -42,5 +60,5 @@ func HttpJobRequest(client *splunk.SplunkClient, method string, spRequest *Searc
 		}
 	}
 
-	return splunk.MakeHttpRequest(client, method, spRequest.Headers, params)
+	return splunk.MakeHttpRequestWithContext(ctx, client, method, endpoint, spRequest.Headers, params)
 }
@@ -0,0 +1,156 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	splunk "github.com/kuro-jojo/splunk-sdk-go/client"
+)
+
+// MetricPoint is a value of a time series
+type MetricPoint struct {
+	Time  time.Time
+	Value float64
+}
+
+// GetNamedMetricFromNewJob returns the value of the field in the first result of a new created job
+func GetNamedMetricFromNewJob(ctx context.Context, client *splunk.SplunkClient, spRequest *SearchRequest, field string) (float64, error) {
+
+	results, err := resultsOfNewJob(ctx, client, spRequest)
+	if err != nil {
+		return -1, err
+	}
+	if len(results.Rows) == 0 {
+		return -1, fmt.Errorf("result is not a metric. Error message : no result found")
+	}
+
+	metric, err := results.Rows[0].Float(field)
+	if err != nil {
+		return -1, fmt.Errorf("convert metric to float failed. Error message : %w", err)
+	}
+	return metric, nil
+}
+
+// GetMetricsFromNewJob returns every numeric field of the first result of a new created job.
+// Internal fields, whose name starts with an underscore, are left out
+func GetMetricsFromNewJob(ctx context.Context, client *splunk.SplunkClient, spRequest *SearchRequest) (map[string]float64, error) {
+
+	results, err := resultsOfNewJob(ctx, client, spRequest)
+	if err != nil {
+		return nil, err
+	}
+	if len(results.Rows) == 0 {
+		return nil, fmt.Errorf("result is not a metric. Error message : no result found")
+	}
+
+	metrics := map[string]float64{}
+	for field, values := range results.Rows[0] {
+		if strings.HasPrefix(field, "_") || len(values) != 1 {
+			continue
+		}
+		if metric, err := strconv.ParseFloat(values[0], 64); err == nil {
+			metrics[field] = metric
+		}
+	}
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("result is not a metric. Error message : no numeric field found")
+	}
+	return metrics, nil
+}
+
+// GetTimeSeriesFromNewJob returns the values of the field over time, as given by a timechart search.
+// When field is empty, the results must have a single field besides the internal ones.
+// The rows without a value for the field, which timechart produces for empty buckets, are skipped
+func GetTimeSeriesFromNewJob(ctx context.Context, client *splunk.SplunkClient, spRequest *SearchRequest, field string) ([]MetricPoint, error) {
+
+	results, err := resultsOfNewJob(ctx, client, spRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	if field == "" {
+		field, err = singleSeries(results)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	points := make([]MetricPoint, 0, len(results.Rows))
+	for i, row := range results.Rows {
+		if row.String(field) == "" {
+			continue
+		}
+		t, err := row.Timestamp()
+		if err != nil {
+			return nil, fmt.Errorf("time series : row %d : %w", i, err)
+		}
+		value, err := row.Float(field)
+		if err != nil {
+			return nil, fmt.Errorf("time series : row %d : %w", i, err)
+		}
+		points = append(points, MetricPoint{Time: t, Value: value})
+	}
+
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Time.Before(points[j].Time)
+	})
+	return points, nil
+}
+
+// singleSeries returns the name of the only field of the results which is not internal
+func singleSeries(results *ResultSet) (string, error) {
+
+	var series []string
+	for _, f := range results.Fields {
+		if !strings.HasPrefix(f.Name, "_") {
+			series = append(series, f.Name)
+		}
+	}
+	if len(series) != 1 {
+		return "", fmt.Errorf("time series : expected a single series but got %v, a field name is needed", series)
+	}
+	return series[0], nil
+}
+
+// resultsOfNewJob creates a job, waits for it if it is asynchronous and returns all of its results
+func resultsOfNewJob(ctx context.Context, client *splunk.SplunkClient, spRequest *SearchRequest) (*ResultSet, error) {
+
+	ctx, span := splunk.StartSearchSpan(ctx, client, OperationSearch)
+	results, err := retrieveResultsOfNewJob(ctx, client, spRequest)
+	if err == nil {
+		span.SetResultCount(int64(len(results.Rows)))
+	}
+	span.End(err)
+	return results, err
+}
+
+func retrieveResultsOfNewJob(ctx context.Context, client *splunk.SplunkClient, spRequest *SearchRequest) (*ResultSet, error) {
+
+	// the search slot is held until the results are read
+	ctx, release, err := splunk.AcquireSearchSlot(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	sid, err := CreateJobWithContext(ctx, client, spRequest, jobsPathv2)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating the job : %w", err)
+	}
+
+	if spRequest.Params.ExecMode == ExecModeNormal {
+		if _, err = WaitForJob(ctx, client, sid, nil); err != nil {
+			return nil, fmt.Errorf("error while waiting for the job : %w", err)
+		}
+	}
+
+	results, err := RetrieveResultSet(ctx, client, sid, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error while handling the results. Error message : %w", err)
+	}
+	return results, nil
+}
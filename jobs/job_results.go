@@ -0,0 +1,148 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	splunk "github.com/kuro-jojo/splunk-sdk-go/client"
+	utils "github.com/kuro-jojo/splunk-sdk-go/pkg/utils"
+)
+
+const defaultResultsPageSize = 1000
+
+// ResultsOptions selects the results of a job to read
+type ResultsOptions struct {
+	// index of the first result to read (0 by default)
+	Offset int
+	// maximum number of results to read, 0 reads all of them
+	Count int
+	// number of results asked to splunk per request (1000 by default)
+	PageSize int
+}
+
+// ForEachResult reads the results of the job page by page and calls fn with each row, in order.
+// The rows are decoded one at a time from the response, so a page is never held in memory.
+// The iteration stops at the first error returned by fn, which is then returned as is
+func ForEachResult(ctx context.Context, client *splunk.SplunkClient, sid string, opts *ResultsOptions, fn func(map[string]string) error) error {
+
+	return forEachResult(ctx, client, sid, opts, fn)
+}
+
+// forEachResult pages through the results of a job, each row being decoded as a T
+func forEachResult[T any](ctx context.Context, client *splunk.SplunkClient, sid string, opts *ResultsOptions, fn func(T) error) error {
+
+	offset, count, pageSize := 0, 0, defaultResultsPageSize
+	if opts != nil {
+		offset, count = opts.Offset, opts.Count
+		if opts.PageSize > 0 {
+			pageSize = opts.PageSize
+		}
+	}
+
+	read := 0
+	for {
+		size := pageSize
+		if count > 0 && count-read < size {
+			size = count - read
+		}
+
+		n, err := readResultsPage(ctx, client, sid, offset+read, size, fn)
+		if err != nil {
+			return err
+		}
+		read += n
+
+		// a short page is the last one
+		if n < size || (count > 0 && read >= count) {
+			return nil
+		}
+	}
+}
+
+// readResultsPage requests count results starting at offset and returns how many rows were given to fn
+func readResultsPage[T any](ctx context.Context, client *splunk.SplunkClient, sid string, offset int, count int, fn func(T) error) (int, error) {
+
+	params := url.Values{}
+	params.Add("output_mode", "json")
+	params.Add("offset", strconv.Itoa(offset))
+	params.Add("count", strconv.Itoa(count))
+
+	endpoint := utils.CreateEndpoint(client, jobsPathv2+sid+"/"+resutltUri)
+
+	resp, err := splunk.MakeHttpRequestWithContext(splunk.ContextWithOperation(ctx, OperationGetJobResults), client, http.MethodGet, endpoint, nil, params)
+	if err != nil {
+		return 0, fmt.Errorf("error while making the get request : %w", err)
+	}
+	defer resp.Body.Close()
+
+	// handle error
+	if err := splunk.CheckResponse(resp); err != nil {
+		return 0, err
+	}
+	// splunk has no content to return while the job is not done
+	if resp.StatusCode == http.StatusNoContent {
+		return 0, fmt.Errorf("job %s : %w", sid, splunk.ErrJobNotDone)
+	}
+
+	return decodeResults(json.NewDecoder(resp.Body), fn)
+}
+
+// decodeResults walks through the json object of a results response and decodes the rows of its results section one by one
+func decodeResults[T any](decoder *json.Decoder, fn func(T) error) (int, error) {
+
+	if err := expectDelim(decoder, '{'); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return n, fmt.Errorf("error while decoding the results : %w", err)
+		}
+
+		// every other section of the response is skipped
+		if key != "results" {
+			var skipped json.RawMessage
+			if err := decoder.Decode(&skipped); err != nil {
+				return n, fmt.Errorf("error while decoding the results : %w", err)
+			}
+			continue
+		}
+
+		if err := expectDelim(decoder, '['); err != nil {
+			return n, err
+		}
+		for decoder.More() {
+			var row T
+			if err := decoder.Decode(&row); err != nil {
+				return n, fmt.Errorf("error while decoding the result %d : %w", n, err)
+			}
+			n++
+			if err := fn(row); err != nil {
+				return n, err
+			}
+		}
+		if err := expectDelim(decoder, ']'); err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+
+	token, err := decoder.Token()
+	if err != nil {
+		return fmt.Errorf("error while decoding the results : %w", err)
+	}
+	if token != delim {
+		return fmt.Errorf("error while decoding the results : expected %s but got %v", delim, token)
+	}
+	return nil
+}
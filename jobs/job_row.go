@@ -0,0 +1,240 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	splunk "github.com/kuro-jojo/splunk-sdk-go/client"
+	utils "github.com/kuro-jojo/splunk-sdk-go/pkg/utils"
+)
+
+// ErrFieldNotFound is returned by the getters of a Row when the row has no value for the field
+var ErrFieldNotFound = errors.New("field not found")
+
+// Row is a result of a job. Every field keeps all of its values, so multi-value fields are not lost
+type Row map[string][]string
+
+// Field describes a field of the results
+type Field struct {
+	Name string `json:"name"`
+	// rank of the field in the by clause of the search, if any
+	GroupbyRank string `json:"groupby_rank,omitempty"`
+}
+
+// ResultSet holds the results of a job with their metadata
+type ResultSet struct {
+	// true if the job is not done and the results are partial
+	Preview bool `json:"preview"`
+	// position of the first row in the results of the job
+	InitOffset int         `json:"init_offset"`
+	Messages   JobMessages `json:"messages"`
+	Fields     []Field     `json:"fields"`
+	Rows       []Row       `json:"results"`
+}
+
+// UnmarshalJSON decodes the values of each field whether splunk sends them as a single value or as a list
+func (r *Row) UnmarshalJSON(data []byte) error {
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	row := make(Row, len(fields))
+	for field, raw := range fields {
+		var values []json.RawMessage
+		if !strings.HasPrefix(strings.TrimSpace(string(raw)), "[") {
+			values = []json.RawMessage{raw}
+		} else if err := json.Unmarshal(raw, &values); err != nil {
+			return fmt.Errorf("field %s : %w", field, err)
+		}
+
+		for _, value := range values {
+			var decoded interface{}
+			if err := json.Unmarshal(value, &decoded); err != nil {
+				return fmt.Errorf("field %s : %w", field, err)
+			}
+			switch v := decoded.(type) {
+			case nil:
+				continue
+			case string:
+				row[field] = append(row[field], v)
+			default:
+				// numbers and booleans keep their json representation
+				row[field] = append(row[field], strings.TrimSpace(string(value)))
+			}
+		}
+	}
+
+	*r = row
+	return nil
+}
+
+// Has reports whether the row has at least one value for the field
+func (r Row) Has(field string) bool {
+
+	return len(r[field]) > 0
+}
+
+// String returns the first value of the field, or an empty string if the field has no value
+func (r Row) String(field string) string {
+
+	if !r.Has(field) {
+		return ""
+	}
+	return r[field][0]
+}
+
+// Strings returns all the values of the field
+func (r Row) Strings(field string) []string {
+
+	return r[field]
+}
+
+// Float returns the first value of the field as a float
+func (r Row) Float(field string) (float64, error) {
+
+	value, err := r.first(field)
+	if err != nil {
+		return 0, err
+	}
+	metric, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("field %s : %w", field, err)
+	}
+	return metric, nil
+}
+
+// Int returns the first value of the field as an integer
+func (r Row) Int(field string) (int64, error) {
+
+	value, err := r.first(field)
+	if err != nil {
+		return 0, err
+	}
+	metric, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("field %s : %w", field, err)
+	}
+	return metric, nil
+}
+
+// Bool returns the first value of the field as a boolean
+func (r Row) Bool(field string) (bool, error) {
+
+	value, err := r.first(field)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("field %s : %w", field, err)
+	}
+	return b, nil
+}
+
+// Time returns the first value of the field as a time.
+// Both the ISO 8601 format used by splunk for _time and epoch seconds are accepted
+func (r Row) Time(field string) (time.Time, error) {
+
+	value, err := r.first(field)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := parseTime(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("field %s : %w", field, err)
+	}
+	return t, nil
+}
+
+// Raw returns the raw event of the row
+func (r Row) Raw() string {
+
+	return r.String("_raw")
+}
+
+// Timestamp returns the time of the event of the row
+func (r Row) Timestamp() (time.Time, error) {
+
+	return r.Time("_time")
+}
+
+func (r Row) first(field string) (string, error) {
+
+	if !r.Has(field) {
+		return "", fmt.Errorf("field %s : %w", field, ErrFieldNotFound)
+	}
+	return r[field][0], nil
+}
+
+func parseTime(value string) (time.Time, error) {
+
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	epoch, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an ISO 8601 time nor an epoch time", value)
+	}
+	seconds, fraction := math.Modf(epoch)
+	return time.Unix(int64(seconds), int64(fraction*1e9)), nil
+}
+
+// RetrieveResultSet returns the results of a job with their metadata.
+// Unlike RetrieveJobResult, multi-value fields are supported. opts.PageSize is ignored, a single request is made
+func RetrieveResultSet(ctx context.Context, client *splunk.SplunkClient, sid string, opts *ResultsOptions) (*ResultSet, error) {
+
+	params := url.Values{}
+	params.Add("output_mode", "json")
+	if opts != nil {
+		params.Add("offset", strconv.Itoa(opts.Offset))
+		params.Add("count", strconv.Itoa(opts.Count))
+	} else {
+		// all the results
+		params.Add("count", "0")
+	}
+
+	endpoint := utils.CreateEndpoint(client, jobsPathv2+sid+"/"+resutltUri)
+
+	resp, err := splunk.MakeHttpRequestWithContext(splunk.ContextWithOperation(ctx, OperationGetJobResults), client, http.MethodGet, endpoint, nil, params)
+	if err != nil {
+		return nil, fmt.Errorf("error while making the get request : %w", err)
+	}
+	defer resp.Body.Close()
+
+	// handle error
+	if err := splunk.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error while getting the body of the get request : %w", err)
+	}
+
+	// splunk has no content to return while the job is not done
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, fmt.Errorf("job %s : %w", sid, splunk.ErrJobNotDone)
+	}
+	results := ResultSet{}
+	if err := json.Unmarshal(body, &results); err != nil {
+		return nil, err
+	}
+	return &results, nil
+}
+
+// ForEachRow is like ForEachResult but the rows keep their multi-value fields
+func ForEachRow(ctx context.Context, client *splunk.SplunkClient, sid string, opts *ResultsOptions, fn func(Row) error) error {
+
+	return forEachResult(ctx, client, sid, opts, fn)
+}
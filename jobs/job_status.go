@@ -0,0 +1,208 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	splunk "github.com/kuro-jojo/splunk-sdk-go/client"
+	utils "github.com/kuro-jojo/splunk-sdk-go/pkg/utils"
+)
+
+// dispatch states of a search job
+const (
+	DispatchStateQueued     = "QUEUED"
+	DispatchStateParsing    = "PARSING"
+	DispatchStateRunning    = "RUNNING"
+	DispatchStatePaused     = "PAUSED"
+	DispatchStateFinalizing = "FINALIZING"
+	DispatchStateFailed     = "FAILED"
+	DispatchStateDone       = "DONE"
+)
+
+// default backoff used while polling the status of a job
+const (
+	defaultPollInitialInterval = 500 * time.Millisecond
+	defaultPollMaxInterval     = 5 * time.Second
+	defaultPollMultiplier      = 1.5
+)
+
+// JobStatus is the progress of a search job as reported by Splunk
+type JobStatus struct {
+	Sid           string      `json:"sid"`
+	DispatchState string      `json:"dispatchState"`
+	DoneProgress  float64     `json:"doneProgress"`
+	ScanCount     int64       `json:"scanCount"`
+	EventCount    int64       `json:"eventCount"`
+	ResultCount   int64       `json:"resultCount"`
+	RunDuration   float64     `json:"runDuration"`
+	IsDone        bool        `json:"isDone"`
+	IsFailed      bool        `json:"isFailed"`
+	IsPaused      bool        `json:"isPaused"`
+	Messages      JobMessages `json:"messages"`
+}
+
+// JobMessage is a message attached by Splunk to a search job
+type JobMessage struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// JobMessages accepts both the list and the empty object splunk uses when a job has no message
+type JobMessages []JobMessage
+
+// UnmarshalJSON decodes a list of messages, anything else is considered as no message
+func (m *JobMessages) UnmarshalJSON(data []byte) error {
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		var messages []JobMessage
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return err
+		}
+		*m = messages
+		return nil
+	}
+	*m = nil
+	return nil
+}
+
+// String returns the texts of the messages, each one prefixed by " : "
+func (m JobMessages) String() string {
+	var texts strings.Builder
+	for _, message := range m {
+		texts.WriteString(" : " + message.Text)
+	}
+	return texts.String()
+}
+
+// PollOptions configures how often WaitForJob asks Splunk for the status of a job
+type PollOptions struct {
+	// delay before the second status request (500ms by default)
+	InitialInterval time.Duration
+	// upper bound of the delay between two status requests (5s by default)
+	MaxInterval time.Duration
+	// factor applied to the delay after each status request (1.5 by default)
+	Multiplier float64
+	// called with every status received until the job is done or failed
+	OnProgress func(JobStatus)
+}
+
+// GetJobStatus returns the current status of the job identified by its SID
+func GetJobStatus(ctx context.Context, client *splunk.SplunkClient, sid string) (*JobStatus, error) {
+
+	// an empty sid would target the collection of the jobs
+	if err := checkSID(sid); err != nil {
+		return nil, err
+	}
+	endpoint := utils.CreateEndpoint(client, jobsPathv2+sid)
+
+	resp, err := GetJobWithContext(splunk.ContextWithOperation(ctx, OperationGetJobStatus), client, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("error while making the get request : %w", err)
+	}
+	defer resp.Body.Close()
+
+	// handle error
+	if err := splunk.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error while getting the body of the get request : %w", err)
+	}
+
+	// only get the content of the job entry
+	type Response struct {
+		Entry []struct {
+			Content JobStatus `json:"content"`
+		} `json:"entry"`
+	}
+
+	job := Response{}
+	if err := json.Unmarshal(body, &job); err != nil {
+		return nil, err
+	}
+	if len(job.Entry) == 0 {
+		return nil, fmt.Errorf("no job found for sid %s", sid)
+	}
+
+	status := job.Entry[0].Content
+	status.Sid = sid
+	return &status, nil
+}
+
+// checkSID rejects an empty sid
+func checkSID(sid string) error {
+
+	if sid == "" {
+		return fmt.Errorf("the sid of the job is required")
+	}
+	return nil
+}
+
+// WaitForJob polls the status of the job until its dispatch state is DONE or FAILED.
+// The delay between two requests grows according to opts, nil uses the default backoff.
+// A failed job is reported with an error matching splunk.ErrJobFailed along with its last status
+func WaitForJob(ctx context.Context, client *splunk.SplunkClient, sid string, opts *PollOptions) (*JobStatus, error) {
+
+	ctx, span := splunk.StartSearchSpan(ctx, client, OperationWaitForJob)
+	span.SetSID(sid)
+	status, err := waitForJob(ctx, client, sid, opts, span)
+	span.End(err)
+	return status, err
+}
+
+func waitForJob(ctx context.Context, client *splunk.SplunkClient, sid string, opts *PollOptions, span *splunk.SearchSpan) (*JobStatus, error) {
+
+	interval, maxInterval, multiplier := defaultPollInitialInterval, defaultPollMaxInterval, defaultPollMultiplier
+	var onProgress func(JobStatus)
+	if opts != nil {
+		if opts.InitialInterval > 0 {
+			interval = opts.InitialInterval
+		}
+		if opts.MaxInterval > 0 {
+			maxInterval = opts.MaxInterval
+		}
+		if opts.Multiplier >= 1 {
+			multiplier = opts.Multiplier
+		}
+		onProgress = opts.OnProgress
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		status, err := GetJobStatus(ctx, client, sid)
+		if err != nil {
+			return nil, err
+		}
+		span.SetDispatchState(status.DispatchState)
+		if onProgress != nil {
+			onProgress(*status)
+		}
+
+		switch {
+		case status.DispatchState == DispatchStateFailed || status.IsFailed:
+			return status, fmt.Errorf("job %s : %w%s", sid, splunk.ErrJobFailed, status.Messages)
+		case status.DispatchState == DispatchStateDone || status.IsDone:
+			span.SetResultCount(status.ResultCount)
+			return status, nil
+		}
+
+		timer.Reset(interval)
+		interval = time.Duration(float64(interval) * multiplier)
+		if interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
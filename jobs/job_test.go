@@ -1,15 +1,28 @@
 package jobs
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	splunk "github.com/kuro-jojo/splunk-sdk-go/client"
-	"github.com/kuro-jojo/splunk-sdk-go/pkg/utils"
 	splunkTest "github.com/kuro-jojo/splunk-sdk-go/pkg/utils"
 
 	"github.com/joho/godotenv"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestGetMetric(t *testing.T) {
@@ -89,8 +102,6 @@ func TestCreateJob(t *testing.T) {
 		true,
 	)
 
-	utils.CreateEndpoint(client, splunkTest.JobsPathv2)
-
 	sid, err := CreateJob(client, &spReq, splunkTest.JobsPathv2)
 
 	if err != nil {
@@ -122,7 +133,6 @@ func TestRetrieveJobResult(t *testing.T) {
 		splunkTest.GetTestToken(),
 		true,
 	)
-	utils.CreateEndpoint(client, splunkTest.JobsPathv2)
 	results, err := RetrieveJobResult(client, "1689673231.191")
 
 	if err != nil {
@@ -138,3 +148,885 @@ func TestRetrieveJobResult(t *testing.T) {
 		t.Fatalf("Expected %v but got %v.", expectedRes, results)
 	}
 }
+
+func TestCreateJobWithContextCancelled(t *testing.T) {
+
+	_ = godotenv.Load(".env")
+
+	release := make(chan struct{})
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// simulate a long blocking search
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := splunk.NewClientAuthenticatedByToken(
+		&http.Client{
+			Timeout: time.Duration(60) * time.Second,
+		},
+		splunkTest.GetTestHostname(server),
+		splunkTest.GetTestPort(server),
+		splunkTest.GetTestToken(),
+		true,
+	)
+
+	spReq := SearchRequest{
+		Params: SearchParams{
+			SearchQuery: "index=main | stats count",
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := CreateJobWithContext(ctx, client, &spReq, splunkTest.JobsPathv2)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected %v but got %v.", context.DeadlineExceeded, err)
+	}
+}
+
+func TestSharedClientConcurrentJobs(t *testing.T) {
+
+	_ = godotenv.Load(".env")
+
+	jsonResponsePOST := `{
+		"sid": "1689673231.191"
+	}`
+
+	jsonResponseGET := `{
+		"results":[{"count":"2566"}]
+	}`
+
+	responses := make([]map[string]interface{}, 2)
+	responses[0] = map[string]interface{}{
+		http.MethodPost: jsonResponsePOST,
+	}
+	responses[1] = map[string]interface{}{
+		http.MethodGet: jsonResponseGET,
+	}
+
+	server := splunkTest.MultitpleMockRequest(responses, true)
+	defer server.Close()
+
+	client := splunk.NewClientAuthenticatedByToken(
+		&http.Client{
+			Timeout: time.Duration(60) * time.Second,
+		},
+		splunkTest.GetTestHostname(server),
+		splunkTest.GetTestPort(server),
+		splunkTest.GetTestToken(),
+		true,
+	)
+
+	spReq := SearchRequest{
+		Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+		Params: SearchParams{
+			SearchQuery: "index=main | stats count",
+		},
+	}
+
+	// the client and the request are shared by every worker
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := GetMetricFromNewJob(client, &spReq)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Got an error : %s", err)
+		}
+	}
+}
+
+func TestGetMetricFromNewAsyncJob(t *testing.T) {
+
+	_ = godotenv.Load(".env")
+
+	jsonResponseRunning := `{
+		"entry":[{"name":"1689673231.191","content":{"dispatchState":"RUNNING","doneProgress":0.5,"scanCount":1200,"resultCount":0,"messages":{}}}]
+	}`
+	jsonResponseDone := `{
+		"entry":[{"name":"1689673231.191","content":{"dispatchState":"DONE","doneProgress":1,"scanCount":2566,"resultCount":1,"isDone":true,"messages":[]}}]
+	}`
+
+	var mu sync.Mutex
+	statusRequests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost:
+			if r.FormValue("exec_mode") != ExecModeNormal {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"sid": "1689673231.191"}`))
+		case strings.HasSuffix(r.URL.Path, "/results"):
+			_, _ = w.Write([]byte(`{"results":[{"count":"2566"}]}`))
+		default:
+			mu.Lock()
+			statusRequests++
+			response := jsonResponseRunning
+			if statusRequests > 2 {
+				response = jsonResponseDone
+			}
+			mu.Unlock()
+			_, _ = w.Write([]byte(response))
+		}
+	}))
+	defer server.Close()
+
+	client := splunk.NewClientAuthenticatedByToken(
+		&http.Client{
+			Timeout: time.Duration(60) * time.Second,
+		},
+		splunkTest.GetTestHostname(server),
+		splunkTest.GetTestPort(server),
+		splunkTest.GetTestToken(),
+		true,
+	)
+
+	spReq := SearchRequest{
+		Params: SearchParams{
+			SearchQuery: "index=main | stats count",
+			ExecMode:    ExecModeNormal,
+		},
+	}
+
+	sid, err := CreateJob(client, &spReq, splunkTest.JobsPathv2)
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+
+	var progress []JobStatus
+	status, err := WaitForJob(context.Background(), client, sid, &PollOptions{
+		InitialInterval: time.Millisecond,
+		OnProgress: func(status JobStatus) {
+			progress = append(progress, status)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+
+	if status.DispatchState != DispatchStateDone || status.ScanCount != 2566 || status.ResultCount != 1 {
+		t.Fatalf("Expected a done job but got %+v.", status)
+	}
+	if len(progress) != 3 || progress[0].DoneProgress != 0.5 {
+		t.Fatalf("Expected 3 progress reports but got %+v.", progress)
+	}
+
+	metric, err := GetMetricFromNewJob(client, &spReq)
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	if metric != 2566 {
+		t.Fatalf("Expected %v but got %v.", 2566, metric)
+	}
+
+	// an empty sid is rejected before any request
+	mu.Lock()
+	statusRequests = 0
+	mu.Unlock()
+	if _, err := GetJobStatus(context.Background(), client, ""); err == nil {
+		t.Fatalf("Expected an error for an empty sid.")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if statusRequests != 0 {
+		t.Fatalf("Expected no status request but got %d.", statusRequests)
+	}
+}
+
+func TestControlJob(t *testing.T) {
+
+	_ = godotenv.Load(".env")
+
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPost:
+			requests = append(requests, r.FormValue("action")+r.FormValue("ttl")+r.FormValue("priority"))
+		default:
+			requests = append(requests, r.Method+" "+r.URL.Path)
+		}
+		if strings.Contains(r.URL.Path, "unknown") {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"messages":[{"type":"ERROR","text":"Unknown sid."}]}`))
+		}
+	}))
+	defer server.Close()
+
+	client := splunk.NewClientAuthenticatedByToken(
+		&http.Client{
+			Timeout: time.Duration(60) * time.Second,
+		},
+		splunkTest.GetTestHostname(server),
+		splunkTest.GetTestPort(server),
+		splunkTest.GetTestToken(),
+		true,
+	)
+
+	ctx := context.Background()
+	sid := "1689673231.191"
+	calls := []func() error{
+		func() error { return PauseJob(ctx, client, sid) },
+		func() error { return UnpauseJob(ctx, client, sid) },
+		func() error { return TouchJob(ctx, client, sid) },
+		func() error { return SetJobTTL(ctx, client, sid, 2*time.Minute) },
+		func() error { return SetJobPriority(ctx, client, sid, 7) },
+		func() error { return FinalizeJob(ctx, client, sid) },
+		func() error { return CancelJob(ctx, client, sid) },
+		func() error { return DeleteJob(ctx, client, sid) },
+	}
+	for _, call := range calls {
+		if err := call(); err != nil {
+			t.Fatalf("Got an error : %s", err)
+		}
+	}
+
+	expectedRequests := []string{"pause", "unpause", "touch", "setttl120", "setpriority7", "finalize", "cancel", "DELETE /services/search/v2/jobs/" + sid}
+	if strings.Join(requests, ",") != strings.Join(expectedRequests, ",") {
+		t.Fatalf("Expected %v but got %v.", expectedRequests, requests)
+	}
+
+	err := CancelJob(ctx, client, "unknown")
+	var splunkErr *splunk.SplunkError
+	if !errors.Is(err, splunk.ErrNotFound) || !errors.As(err, &splunkErr) || !splunkErr.HasMessageType(splunk.MessageTypeError) || !strings.Contains(err.Error(), "Unknown sid.") {
+		t.Fatalf("Expected an unknown sid error but got %v.", err)
+	}
+	if err := SetJobPriority(ctx, client, sid, 11); err == nil {
+		t.Fatalf("Expected an error for an out of range priority.")
+	}
+
+	// an empty sid is rejected before any request
+	mu.Lock()
+	requests = nil
+	mu.Unlock()
+	if err := CancelJob(ctx, client, ""); err == nil {
+		t.Fatalf("Expected an error for an empty sid.")
+	}
+	if err := DeleteJob(ctx, client, ""); err == nil {
+		t.Fatalf("Expected an error for an empty sid.")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 0 {
+		t.Fatalf("Expected no request but got %v.", requests)
+	}
+}
+
+func TestControlJobRetry(t *testing.T) {
+
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Method + " " + r.FormValue("action")
+		mu.Lock()
+		attempts[key]++
+		mu.Unlock()
+		// longer than the timeout of the http client : the job is cancelled or deleted but the response is lost
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client, err := splunk.New(splunkTest.GetTestHostname(server),
+		splunk.WithPort(splunkTest.GetTestPort(server)),
+		splunk.WithScheme("http"),
+		splunk.WithToken(splunkTest.GetTestToken()),
+		splunk.WithHTTPClient(&http.Client{Timeout: 50 * time.Millisecond}),
+		splunk.WithRetry(splunk.RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+
+	ctx := context.Background()
+	sid := "1689673231.191"
+	_ = CancelJob(ctx, client, sid)
+	_ = DeleteJob(ctx, client, sid)
+	_ = TouchJob(ctx, client, sid)
+
+	mu.Lock()
+	defer mu.Unlock()
+	// the cancellation and the deletion are not sent again, unlike the actions with the same effect when run twice
+	expected := map[string]int{"POST cancel": 1, "DELETE ": 1, "POST touch": 4}
+	if fmt.Sprint(attempts) != fmt.Sprint(expected) {
+		t.Fatalf("Expected %v attempts but got %v.", expected, attempts)
+	}
+}
+
+func TestForEachResult(t *testing.T) {
+
+	_ = godotenv.Load(".env")
+
+	const totalResults = 25
+	var mu sync.Mutex
+	var pages []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+
+		mu.Lock()
+		pages = append(pages, r.URL.Query().Get("offset")+":"+r.URL.Query().Get("count"))
+		mu.Unlock()
+
+		var rows []string
+		for i := offset; i < offset+count && i < totalResults; i++ {
+			rows = append(rows, `{"id":"`+strconv.Itoa(i)+`"}`)
+		}
+		_, _ = w.Write([]byte(`{"preview":false,"init_offset":` + strconv.Itoa(offset) + `,"messages":[],"fields":[{"name":"id"}],"results":[` + strings.Join(rows, ",") + `],"highlighted":{}}`))
+	}))
+	defer server.Close()
+
+	client := splunk.NewClientAuthenticatedByToken(
+		&http.Client{
+			Timeout: time.Duration(60) * time.Second,
+		},
+		splunkTest.GetTestHostname(server),
+		splunkTest.GetTestPort(server),
+		splunkTest.GetTestToken(),
+		true,
+	)
+
+	var ids []string
+	err := ForEachResult(context.Background(), client, "1689673231.191", &ResultsOptions{PageSize: 10}, func(row map[string]string) error {
+		ids = append(ids, row["id"])
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	if len(ids) != totalResults || ids[0] != "0" || ids[totalResults-1] != "24" {
+		t.Fatalf("Expected %d ordered results but got %v.", totalResults, ids)
+	}
+	if strings.Join(pages, ",") != "0:10,10:10,20:10" {
+		t.Fatalf("Expected 3 pages but got %v.", pages)
+	}
+
+	ids, pages = nil, nil
+	err = ForEachResult(context.Background(), client, "1689673231.191", &ResultsOptions{Offset: 5, Count: 12, PageSize: 10}, func(row map[string]string) error {
+		ids = append(ids, row["id"])
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	if len(ids) != 12 || ids[0] != "5" || ids[11] != "16" {
+		t.Fatalf("Expected the results 5 to 16 but got %v.", ids)
+	}
+	if strings.Join(pages, ",") != "5:10,15:2" {
+		t.Fatalf("Expected 2 pages but got %v.", pages)
+	}
+
+	errStop := errors.New("stop")
+	read := 0
+	err = ForEachResult(context.Background(), client, "1689673231.191", nil, func(row map[string]string) error {
+		read++
+		if read == 3 {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) || read != 3 {
+		t.Fatalf("Expected the iteration to stop after 3 results but got %v after %d.", err, read)
+	}
+
+	// the results of a job which is not done are not ready
+	notDone := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer notDone.Close()
+	client = splunk.NewClientAuthenticatedByToken(&http.Client{}, splunkTest.GetTestHostname(notDone), splunkTest.GetTestPort(notDone), splunkTest.GetTestToken(), true)
+
+	read = 0
+	err = ForEachResult(context.Background(), client, "1689673231.191", nil, func(row map[string]string) error {
+		read++
+		return nil
+	})
+	if !errors.Is(err, splunk.ErrJobNotDone) || !strings.Contains(err.Error(), "1689673231.191") || read != 0 {
+		t.Fatalf("Expected %v but got %v after %d results.", splunk.ErrJobNotDone, err, read)
+	}
+}
+
+func TestExport(t *testing.T) {
+
+	_ = godotenv.Load(".env")
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/jobs/export") || r.FormValue("earliest_time") != "-1h" || r.FormValue("search") != "search index=main | stats count by host" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"preview":true,"offset":0,"result":{"host":"web-1","count":"10"}}
+{"messages":[{"type":"INFO","text":"Your timerange was substituted."}]}
+{"preview":false,"offset":0,"result":{"host":"web-1","count":"12"}}
+{"preview":false,"offset":1,"lastrow":true,"result":{"host":"web-2","count":"7"}}
+`))
+	}))
+	defer server.Close()
+
+	client := splunk.NewClientAuthenticatedByToken(
+		&http.Client{
+			Timeout: time.Duration(60) * time.Second,
+		},
+		splunkTest.GetTestHostname(server),
+		splunkTest.GetTestPort(server),
+		splunkTest.GetTestToken(),
+		true,
+	)
+
+	spReq := SearchRequest{
+		Params: SearchParams{
+			SearchQuery:  "index=main | stats count by host",
+			EarliestTime: "-1h",
+		},
+	}
+
+	reader, err := Export(context.Background(), client, &spReq)
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	defer reader.Close()
+
+	var results []ExportResult
+	for reader.Next() {
+		results = append(results, reader.Result())
+	}
+	if err := reader.Err(); err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 rows but got %v.", results)
+	}
+	if !results[0].Preview || results[1].Preview || results[1].Result.String("count") != "12" {
+		t.Fatalf("Expected a preview row then a final row but got %v.", results)
+	}
+	if !results[2].LastRow || results[2].Offset != 1 || results[2].Result.String("host") != "web-2" {
+		t.Fatalf("Expected the last row to be marked but got %v.", results[2])
+	}
+}
+
+func TestRetrieveResultSet(t *testing.T) {
+
+	_ = godotenv.Load(".env")
+
+	jsonResponseGET := `{
+		"preview":false,
+		"init_offset":0,
+		"messages":[],
+		"fields":[{"name":"host","groupby_rank":"0"},{"name":"values(status)"},{"name":"avg"},{"name":"_time"},{"name":"_raw"}],
+		"results":[
+			{"host":"web-1","values(status)":["200","404","500"],"avg":"12.5","count":3,"_time":"2023-07-18T10:00:00.000+00:00","_raw":"GET /index.html"},
+			{"host":"web-2","values(status)":"200","avg":null,"_time":"1689674400.5"}
+		]
+	}`
+	server := splunkTest.MockRequest(jsonResponseGET, true)
+	defer server.Close()
+
+	client := splunk.NewClientAuthenticatedByToken(
+		&http.Client{
+			Timeout: time.Duration(60) * time.Second,
+		},
+		splunkTest.GetTestHostname(server),
+		splunkTest.GetTestPort(server),
+		splunkTest.GetTestToken(),
+		true,
+	)
+
+	results, err := RetrieveResultSet(context.Background(), client, "1689673231.191", nil)
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+
+	if len(results.Fields) != 5 || results.Fields[0].Name != "host" || results.Fields[0].GroupbyRank != "0" {
+		t.Fatalf("Expected the fields metadata but got %v.", results.Fields)
+	}
+	if len(results.Rows) != 2 {
+		t.Fatalf("Expected 2 rows but got %v.", results.Rows)
+	}
+
+	row := results.Rows[0]
+	if statuses := row.Strings("values(status)"); strings.Join(statuses, ",") != "200,404,500" {
+		t.Fatalf("Expected the 3 values of the multi-value field but got %v.", statuses)
+	}
+	if avg, err := row.Float("avg"); err != nil || avg != 12.5 {
+		t.Fatalf("Expected %v but got %v (%v).", 12.5, avg, err)
+	}
+	if count, err := row.Int("count"); err != nil || count != 3 {
+		t.Fatalf("Expected %v but got %v (%v).", 3, count, err)
+	}
+	if eventTime, err := row.Timestamp(); err != nil || !eventTime.Equal(time.Date(2023, 7, 18, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected the _time of the event but got %v (%v).", eventTime, err)
+	}
+	if row.Raw() != "GET /index.html" {
+		t.Fatalf("Expected the _raw of the event but got %v.", row.Raw())
+	}
+
+	row = results.Rows[1]
+	if statuses := row.Strings("values(status)"); len(statuses) != 1 || statuses[0] != "200" {
+		t.Fatalf("Expected a single value but got %v.", statuses)
+	}
+	if _, err := row.Float("avg"); !errors.Is(err, ErrFieldNotFound) {
+		t.Fatalf("Expected %v but got %v.", ErrFieldNotFound, err)
+	}
+	if eventTime, err := row.Timestamp(); err != nil || eventTime.UnixMilli() != 1689674400500 {
+		t.Fatalf("Expected the epoch _time of the event but got %v (%v).", eventTime, err)
+	}
+
+	// the results of a job which is not done are not ready
+	notDone := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer notDone.Close()
+	client = splunk.NewClientAuthenticatedByToken(&http.Client{}, splunkTest.GetTestHostname(notDone), splunkTest.GetTestPort(notDone), splunkTest.GetTestToken(), true)
+
+	results, err = RetrieveResultSet(context.Background(), client, "1689673231.191", nil)
+	if !errors.Is(err, splunk.ErrJobNotDone) || !strings.Contains(err.Error(), "1689673231.191") || results != nil {
+		t.Fatalf("Expected %v but got %v with %v.", splunk.ErrJobNotDone, err, results)
+	}
+}
+
+func TestDecodeResults(t *testing.T) {
+
+	type hostStats struct {
+		Host     string    `splunk:"host"`
+		Count    int       `splunk:"count,required"`
+		Avg      *float64  `splunk:"avg"`
+		Statuses []int     `splunk:"values(status)"`
+		Up       bool      `splunk:"up"`
+		Last     time.Time `splunk:"_time"`
+		Ignored  string    `splunk:"-"`
+		Raw      string    `splunk:"_raw"`
+	}
+
+	rows := []Row{
+		{"host": {"web-1"}, "count": {"3"}, "avg": {"12.5"}, "values(status)": {"200", "404"}, "up": {"true"}, "_time": {"2023-07-18T10:00:00.000+00:00"}, "-": {"x"}},
+		{"host": {"web-2"}, "count": {"1"}, "values(status)": {"500"}},
+	}
+
+	stats, err := DecodeResults[hostStats](rows)
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+
+	first := stats[0]
+	if first.Host != "web-1" || first.Count != 3 || first.Avg == nil || *first.Avg != 12.5 || !first.Up || first.Ignored != "" {
+		t.Fatalf("Unexpected decoded row %+v.", first)
+	}
+	if len(first.Statuses) != 2 || first.Statuses[1] != 404 {
+		t.Fatalf("Expected the values of the multi-value field but got %v.", first.Statuses)
+	}
+	if !first.Last.Equal(time.Date(2023, 7, 18, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected the _time of the event but got %v.", first.Last)
+	}
+	if stats[1].Avg != nil || stats[1].Statuses[0] != 500 {
+		t.Fatalf("Unexpected decoded row %+v.", stats[1])
+	}
+
+	rows[1]["count"] = []string{"many"}
+	_, err = DecodeResults[hostStats](rows)
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Row != 1 || decodeErr.Field != "count" || decodeErr.StructField != "Count" {
+		t.Fatalf("Expected a decoding error on the field count of the row 1 but got %v.", err)
+	}
+
+	delete(rows[1], "count")
+	_, err = DecodeResults[hostStats](rows)
+	if !errors.Is(err, ErrFieldNotFound) {
+		t.Fatalf("Expected %v but got %v.", ErrFieldNotFound, err)
+	}
+}
+
+func TestGetMetricsFromNewJob(t *testing.T) {
+
+	_ = godotenv.Load(".env")
+
+	jsonResponsePOST := `{
+		"sid": "1689673231.191"
+	}`
+
+	jsonResponseStats := `{
+		"fields":[{"name":"count"},{"name":"avg"},{"name":"host"}],
+		"results":[{"count":"2566","avg":"0.25","host":"web-1","_time":"1689674400"},{"count":"12","avg":"1","host":"web-2"}]
+	}`
+
+	jsonResponseTimechart := `{
+		"fields":[{"name":"_time"},{"name":"count"},{"name":"_span"}],
+		"results":[
+			{"_time":"2023-07-18T10:00:00.000+00:00","count":"5","_span":"60"},
+			{"_time":"2023-07-18T10:01:00.000+00:00","_span":"60"},
+			{"_time":"2023-07-18T10:02:00.000+00:00","count":"7","_span":"60"}
+		]
+	}`
+
+	for _, test := range []struct {
+		name     string
+		response string
+		check    func(client *splunk.SplunkClient, spReq *SearchRequest) error
+	}{
+		{
+			name:     "named metric",
+			response: jsonResponseStats,
+			check: func(client *splunk.SplunkClient, spReq *SearchRequest) error {
+				metric, err := GetNamedMetricFromNewJob(context.Background(), client, spReq, "avg")
+				if err != nil || metric != 0.25 {
+					return fmt.Errorf("expected %v but got %v (%v)", 0.25, metric, err)
+				}
+				return nil
+			},
+		},
+		{
+			name:     "every metric",
+			response: jsonResponseStats,
+			check: func(client *splunk.SplunkClient, spReq *SearchRequest) error {
+				metrics, err := GetMetricsFromNewJob(context.Background(), client, spReq)
+				if err != nil || len(metrics) != 2 || metrics["count"] != 2566 || metrics["avg"] != 0.25 {
+					return fmt.Errorf("expected the count and the avg but got %v (%v)", metrics, err)
+				}
+				return nil
+			},
+		},
+		{
+			name:     "time series",
+			response: jsonResponseTimechart,
+			check: func(client *splunk.SplunkClient, spReq *SearchRequest) error {
+				points, err := GetTimeSeriesFromNewJob(context.Background(), client, spReq, "")
+				if err != nil || len(points) != 2 || points[1].Value != 7 || points[1].Time.Minute() != 2 {
+					return fmt.Errorf("expected 2 points but got %v (%v)", points, err)
+				}
+				return nil
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			responses := make([]map[string]interface{}, 2)
+			responses[0] = map[string]interface{}{
+				http.MethodPost: jsonResponsePOST,
+			}
+			responses[1] = map[string]interface{}{
+				http.MethodGet: test.response,
+			}
+
+			server := splunkTest.MultitpleMockRequest(responses, true)
+			defer server.Close()
+
+			client := splunk.NewClientAuthenticatedByToken(
+				&http.Client{
+					Timeout: time.Duration(60) * time.Second,
+				},
+				splunkTest.GetTestHostname(server),
+				splunkTest.GetTestPort(server),
+				splunkTest.GetTestToken(),
+				true,
+			)
+
+			spReq := SearchRequest{
+				Params: SearchParams{
+					SearchQuery: "index=main | stats count avg(duration) as avg by host",
+				},
+			}
+
+			if err := test.check(client, &spReq); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestMaxConcurrentSearches(t *testing.T) {
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	operations := map[string]int{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// a search runs from its creation until its results are read
+		if r.Method == http.MethodPost {
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			_, _ = w.Write([]byte(`{"sid":"1689673231.191"}`))
+			return
+		}
+		running--
+		_, _ = w.Write([]byte(`{"results":[{"count":"2566"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := splunk.New(splunkTest.GetTestHostname(server),
+		splunk.WithPort(splunkTest.GetTestPort(server)),
+		splunk.WithToken(splunkTest.GetTestToken()),
+		splunk.WithInsecureSkipVerify(),
+		splunk.WithMaxConcurrentSearches(2),
+		splunk.WithMiddleware(func(next splunk.RequestHandler) splunk.RequestHandler {
+			return func(req *http.Request) (*http.Response, error) {
+				mu.Lock()
+				operations[splunk.OperationFromContext(req.Context())]++
+				mu.Unlock()
+				return next(req)
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+
+	spReq := SearchRequest{
+		Params: SearchParams{
+			SearchQuery: "index=main | stats count",
+		},
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := GetMetricFromNewJobWithContext(context.Background(), client, &spReq)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Got an error : %s", err)
+		}
+	}
+	if maxRunning > 2 {
+		t.Fatalf("Expected at most 2 concurrent searches but got %d.", maxRunning)
+	}
+	if len(operations) != 2 || operations[OperationCreateJob] != 10 || operations[OperationGetJobResults] != 10 {
+		t.Fatalf("Expected 10 creations and 10 reads of results but got %v.", operations)
+	}
+}
+
+func TestTelemetry(t *testing.T) {
+
+	var mu sync.Mutex
+	statusRequests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPost && r.FormValue("search") == "search index=missing":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"messages":[{"type":"FATAL","text":"Unknown search command"}]}`))
+		case r.Method == http.MethodPost:
+			_, _ = w.Write([]byte(`{"sid": "1689673231.191"}`))
+		case strings.HasSuffix(r.URL.Path, "/results"):
+			_, _ = w.Write([]byte(`{"results":[{"count":"2566"}]}`))
+		default:
+			statusRequests++
+			state := DispatchStateRunning
+			if statusRequests > 1 {
+				state = DispatchStateDone
+			}
+			_, _ = fmt.Fprintf(w, `{"entry":[{"content":{"dispatchState":"%s","resultCount":1}}]}`, state)
+		}
+	}))
+	defer server.Close()
+
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	client, err := splunk.New(splunkTest.GetTestHostname(server),
+		splunk.WithPort(splunkTest.GetTestPort(server)),
+		splunk.WithToken(splunkTest.GetTestToken()),
+		splunk.WithInsecureSkipVerify(),
+		splunk.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+		splunk.WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	)
+	if err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+
+	spReq := SearchRequest{
+		Params: SearchParams{
+			SearchQuery: "index=main | stats count",
+			ExecMode:    ExecModeNormal,
+		},
+	}
+	if _, err := GetMetricFromNewJobWithContext(context.Background(), client, &spReq); err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	spReq.Params.SearchQuery = "index=missing"
+	if _, err := GetMetricFromNewJobWithContext(context.Background(), client, &spReq); err == nil {
+		t.Fatalf("Expected an error for the unknown search.")
+	}
+
+	// the first search : its span and the spans of its requests
+	ended := spans.Ended()
+	var names []string
+	for _, span := range ended[:5] {
+		names = append(names, span.Name())
+	}
+	expected := []string{OperationCreateJob, OperationGetJobStatus, OperationGetJobStatus, OperationGetJobResults, OperationSearch}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected the spans %v but got %v.", expected, names)
+	}
+	search := ended[4]
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, attr := range search.Attributes() {
+		attrs[attr.Key] = attr.Value
+	}
+	if attrs[splunk.AttributeSID].AsString() != "1689673231.191" || attrs[splunk.AttributeDispatchState].AsString() != DispatchStateDone || attrs[splunk.AttributeResultCount].AsInt64() != 1 {
+		t.Fatalf("Expected the sid, the dispatch state and the result count of the search but got %v.", search.Attributes())
+	}
+	for _, span := range ended[:4] {
+		if span.Parent().SpanID() != search.SpanContext().SpanID() || span.SpanKind() != trace.SpanKindClient {
+			t.Fatalf("Expected the request %s to be a child of the search.", span.Name())
+		}
+	}
+	if status := ended[6].Status(); ended[6].Name() != OperationSearch || status.Code != codes.Error {
+		t.Fatalf("Expected a failed search but got %s %v.", ended[6].Name(), status)
+	}
+
+	var metrics metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &metrics); err != nil {
+		t.Fatalf("Got an error : %s", err)
+	}
+	values := map[string]int64{}
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Histogram[float64]:
+				for _, point := range data.DataPoints {
+					values[m.Name] += int64(point.Count)
+				}
+			case metricdata.Histogram[int64]:
+				for _, point := range data.DataPoints {
+					values[m.Name] += point.Sum
+				}
+			case metricdata.Sum[int64]:
+				for _, point := range data.DataPoints {
+					values[m.Name] += point.Value
+				}
+			}
+		}
+	}
+	// the failed search counts as an error, as its request
+	if values["splunk.search.duration"] != 2 || values["splunk.search.results"] != 1 || values["splunk.errors"] != 2 {
+		t.Fatalf("Expected 2 searches, 1 result and 2 errors but got %v.", values)
+	}
+}
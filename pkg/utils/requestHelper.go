@@ -1,7 +1,6 @@
 package utils
 
 import (
-	"net"
 	"strings"
 
 	splunk "github.com/kuro-jojo/splunk-sdk-go/client"
@@ -25,17 +24,8 @@ func ValidateAlertQuery(alertQuery string) string {
 	return alertQuery
 }
 
-func CreateEndpoint(client *splunk.SplunkClient, service string) {
-	host := client.Host
-	port := client.Port
+// CreateEndpoint returns the url of the given service on the Splunk instance of the client
+func CreateEndpoint(client *splunk.SplunkClient, service string) string {
 
-	switch {
-	case strings.HasPrefix(host, "https://"):
-		host = strings.Replace(host, "https://", "", 1)
-	case strings.HasPrefix(host, "http://"):
-		host = strings.Replace(host, "http://", "", 1)
-	}
-
-	client.Endpoint = "https://" + net.JoinHostPort(host, port) + "/" + service
-	client.Endpoint = strings.ReplaceAll(client.Endpoint, " ", "")
+	return splunk.CreateEndpoint(client, service)
 }